@@ -1,91 +1,165 @@
 package main
 
 import (
-	"crypto/sha256"
-	"fmt"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"flag"
 	"io"
 	"log"
-	"net/http"
+	"net"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/danielNemeth19/http-protocol/internal/accesslog"
+	"github.com/danielNemeth19/http-protocol/internal/auth"
+	"github.com/danielNemeth19/http-protocol/internal/cors"
+	"github.com/danielNemeth19/http-protocol/internal/jsonhttp"
+	"github.com/danielNemeth19/http-protocol/internal/metrics"
+	"github.com/danielNemeth19/http-protocol/internal/proxy"
+	"github.com/danielNemeth19/http-protocol/internal/ratelimit"
 	"github.com/danielNemeth19/http-protocol/internal/request"
 	"github.com/danielNemeth19/http-protocol/internal/response"
 	"github.com/danielNemeth19/http-protocol/internal/server"
-	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/sse"
+	"github.com/danielNemeth19/http-protocol/internal/vhost"
+	"github.com/danielNemeth19/http-protocol/internal/websocket"
 )
 
-const port = 42069
+var (
+	upstreams    = flag.String("upstream", "http://localhost:8080", "comma-separated upstream URLs proxied under /httpbin")
+	lbStrategy   = flag.String("lb", "round-robin", "load balancing strategy: round-robin, least-conn or hash")
+	healthPath   = flag.String("health-path", "", "path probed on every upstream; empty disables active health checks")
+	forward      = flag.Bool("forward-proxy", false, "act as a forward proxy for absolute-form and CONNECT requests")
+	proxyAuth    = flag.String("proxy-auth", "", "user:password required from forward proxy clients")
+	proxyOpen    = flag.Bool("proxy-no-auth", false, "run the forward proxy without -proxy-auth, open to anyone who can reach it")
+	accessLog    = flag.String("access-log", "", "access log file, - for stdout; empty disables access logging")
+	logFormat    = flag.String("access-log-format", "combined", "access log format: common, combined or json")
+	logMaxSize   = flag.Int64("access-log-max-size", 100, "rotate the access log file once it reaches this many MB")
+	logBackups   = flag.Int("access-log-backups", 5, "rotated access log files to keep")
+	metricsPath  = flag.String("metrics-path", "/metrics", "path serving Prometheus metrics; empty disables metrics")
+	maxConns     = flag.Int("max-conns", 0, "maximum connections served at once; 0 means no limit")
+	maxConnsIP   = flag.Int("max-conns-per-ip", 0, "maximum connections served at once per client IP; 0 means no limit")
+	queueConns   = flag.Bool("queue-conns", false, "queue connections over -max-conns instead of answering 503")
+	rateLimit    = flag.Int("rate-limit", 0, "requests allowed per -rate-window and client; 0 disables rate limiting")
+	rateWindow   = flag.Duration("rate-window", time.Second, "window of -rate-limit")
+	rateAlgo     = flag.String("rate-algorithm", "token-bucket", "rate limiting algorithm: token-bucket or sliding-window")
+	rateKey      = flag.String("rate-limit-key", "ip", "what requests are limited by: ip, route or header:<name>")
+	htpasswd     = flag.String("htpasswd", "", "htpasswd file of users allowed in with Basic auth; empty disables authentication")
+	authRealm    = flag.String("auth-realm", "http-protocol", "realm of the Basic auth challenge")
+	corsOrigins  = flag.String("cors-origins", "", "comma-separated origins allowed to make cross-origin requests, * for any; empty disables CORS")
+	corsCreds    = flag.Bool("cors-credentials", false, "let cross-origin requests from -cors-origins carry cookies and HTTP authentication; not allowed with *")
+	sites        = flag.String("sites", "", "comma-separated host=directory pairs served as static sites, e.g. docs.example.com=./docs")
+	addr         = flag.String("addr", ":42069", "TCP address to listen on; empty disables TCP")
+	unixSocket   = flag.String("unix", "", "also listen on this Unix domain socket")
+	unixMode     = flag.Uint("unix-mode", 0o660, "file permissions of the -unix socket")
+	writeTimeout = flag.Duration("write-timeout", 0, "cancel requests whose response is not written within this duration; 0 disables")
+	proxyProto   = flag.String("proxy-protocol", "", "comma-separated networks of load balancers sending a PROXY protocol header, e.g. 10.0.0.0/8; empty disables")
+	drainTimeout = flag.Duration("drain-timeout", 30*time.Second, "on stop or restart, wait this long for requests being served to finish")
+)
 
-func myHandler(w *response.Writer, req *request.Request) {
-	target := req.RequestLine.RequestTarget
-	if target == "/yourproblem" {
-		resp := server.HandlerError{
-			Code:    response.StatusBadRequest,
-			Message: response.BadRequestHTML,
-		}
-		w.WriteStatusLine(resp.Code)
-		headers := response.GetDefaultHeaders(len(resp.Message))
-		headers = response.ReplaceHeader(map[string]string{"Content-Type": "text/html"}, headers)
-		w.WriteHeaders(headers)
-		w.WriteBody([]byte(resp.Message))
+var (
+	httpbinProxy server.Handler
+	forwardProxy *proxy.ForwardProxy
+	registry     *metrics.Registry
+)
+
+// writeErrorPage serves the error as HTML or as RFC 9457 problem details,
+// whichever the client prefers; HTML is the fallback when neither is
+// acceptable.
+func writeErrorPage(w *response.Writer, req *request.Request, code response.StatusCode, html string) {
+	contentType := req.Headers.NegotiateContentType([]string{"text/html", "application/json", jsonhttp.ProblemContentType})
+	if contentType == "application/json" || contentType == jsonhttp.ProblemContentType {
+		w.AddHeader("Vary", "Accept")
+		jsonhttp.WriteProblem(w, jsonhttp.NewProblem(code, ""))
 		return
 	}
-	if target == "/myproblem" {
-		resp := server.HandlerError{
-			Code:    response.StatusInternalServerError,
-			Message: response.InternalServerErrorHTML,
-		}
-		w.WriteStatusLine(resp.Code)
-		headers := response.GetDefaultHeaders(len(resp.Message))
-		headers = response.ReplaceHeader(map[string]string{"Content-Type": "text/html"}, headers)
-		w.WriteHeaders(headers)
-		w.WriteBody([]byte(resp.Message))
+	w.WriteStatusLine(code)
+	headers := response.GetDefaultHeaders(len(html))
+	headers = response.ReplaceHeader(map[string]string{"Content-Type": "text/html"}, headers)
+	headers.Set("Vary", "Accept")
+	w.WriteHeaders(headers)
+	w.WriteBody([]byte(html))
+}
+
+func echoWebSocket(w *response.Writer, req *request.Request) {
+	conn, err := websocket.Upgrade(w, req)
+	if err != nil {
+		log.Println(err)
 		return
 	}
-	toGet, found := strings.CutPrefix(target, "/httpbin")
-	if found {
-		// toTarget := "https://httpbin.org" + toGet
-		toTarget := "http://localhost:8080" + toGet
-		resp, _ := http.Get(toTarget)
-		w.WriteStatusLine(response.StatusOK)
-		h := response.GetChunkedHeaders()
-		h.Set("Trailer", "X-Content-Sha256")
-		h.Set("Trailer", "X-Content-Length")
-		w.WriteHeaders(h)
-		buf := make([]byte, 1024)
-		var content []byte
-		for {
-			n, err := resp.Body.Read(buf)
-			if n > 0 {
-				content = append(content, buf[:n]...)
-			}
-			if err == io.EOF {
-				if n > 0 {
-					w.WriteChunkedBody(buf[:n])
-				}
-				w.WriteChunkedBodyDone()
-				hash := sha256.Sum256(content)
-				trailers := headers.NewHeaders()
-				trailers.Set("X-Content-Sha256", fmt.Sprintf("%x", hash))
-				trailers.Set("X-Content-Length", strconv.Itoa(len(content)))
-				w.WriteTrailers(trailers)
-				return
-			}
-			if err != nil {
-				fmt.Println(err)
-				return
-			}
-			n, err = w.WriteChunkedBody(buf[:n])
+	defer conn.Close(websocket.CloseNormalClosure, "")
+	for {
+		msgType, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if err := conn.WriteMessage(msgType, data); err != nil {
+			return
+		}
+	}
+}
+
+// streamClock sends the time every second as server-sent events numbered
+// from 1, resuming after the last one a reconnecting client received.
+func streamClock(w *response.Writer, req *request.Request) {
+	stream, err := sse.Start(w, req)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	defer stream.Close()
+	n, _ := strconv.Atoi(stream.LastEventID())
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return
+		case now := <-ticker.C:
+			n++
+			err := stream.Send(sse.Event{ID: strconv.Itoa(n), Event: "tick", Data: now.Format(time.RFC3339)})
 			if err != nil {
-				fmt.Println(err)
 				return
 			}
 		}
 	}
+}
+
+func myHandler(w *response.Writer, req *request.Request) {
+	target := req.RequestLine.RequestTarget
+	if forwardProxy != nil && proxy.IsProxyRequest(req) {
+		forwardProxy.Handle(w, req)
+		return
+	}
+	if target == "/yourproblem" {
+		writeErrorPage(w, req, response.StatusBadRequest, response.BadRequestHTML)
+		return
+	}
+	if target == "/myproblem" {
+		writeErrorPage(w, req, response.StatusInternalServerError, response.InternalServerErrorHTML)
+		return
+	}
+	if registry != nil && target == *metricsPath {
+		registry.Handle(w, req)
+		return
+	}
+	if target == "/ws" {
+		echoWebSocket(w, req)
+		return
+	}
+	if target == "/events" {
+		streamClock(w, req)
+		return
+	}
+	if strings.HasPrefix(target, "/httpbin") {
+		httpbinProxy(w, req)
+		return
+	}
 	w.WriteStatusLine(response.StatusOK)
 	headers := response.GetDefaultHeaders(len(response.SuccessHTML))
 	headers = response.ReplaceHeader(map[string]string{"Content-Type": "text/html"}, headers)
@@ -93,17 +167,198 @@ func myHandler(w *response.Writer, req *request.Request) {
 	w.WriteBody([]byte(response.SuccessHTML))
 }
 
+// listen takes over the listeners of the process that restarted into this
+// one, or those passed by systemd socket activation or, if there are none,
+// opens the ones configured with -addr and -unix.
+func listen() ([]net.Listener, error) {
+	listeners, err := server.InheritedListeners()
+	if err != nil || len(listeners) > 0 {
+		return listeners, err
+	}
+	listeners, err = server.SystemdListeners()
+	if err != nil || len(listeners) > 0 {
+		return listeners, err
+	}
+	if *addr != "" {
+		l, err := net.Listen("tcp", *addr)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	if *unixSocket != "" {
+		l, err := server.ListenUnix(*unixSocket, os.FileMode(*unixMode))
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	if len(listeners) == 0 {
+		return nil, errors.New("Nothing to listen on, set -addr or -unix")
+	}
+	return listeners, nil
+}
+
 func main() {
-	server, err := server.Serve(port, myHandler)
+	flag.Parse()
+	strategy, err := proxy.ParseStrategy(*lbStrategy)
 	if err != nil {
-		log.Fatalf("Error starting server: %v", err)
+		log.Fatalf("Error configuring proxy: %v", err)
+	}
+	pool, err := proxy.NewPool(strings.Split(*upstreams, ","), strategy)
+	if err != nil {
+		log.Fatalf("Error configuring proxy: %v", err)
+	}
+	if *healthPath != "" {
+		pool.StartHealthChecks(*healthPath, 10*time.Second, 2*time.Second)
+		defer pool.Stop()
+	}
+	httpbinProxy = server.StripPrefix("/httpbin", proxy.NewBalancedProxy(pool).Handle)
+	if *forward {
+		if *proxyAuth == "" && !*proxyOpen {
+			log.Fatalf("Error configuring forward proxy: -proxy-auth is required unless -proxy-no-auth is given")
+		}
+		forwardProxy = &proxy.ForwardProxy{}
+		if *proxyAuth != "" {
+			user, password, found := strings.Cut(*proxyAuth, ":")
+			if !found {
+				log.Fatalf("Error configuring forward proxy: -proxy-auth must be user:password")
+			}
+			forwardProxy.Authenticate = func(u, p string) bool {
+				return subtle.ConstantTimeCompare([]byte(u+":"+p), []byte(user+":"+password)) == 1
+			}
+		}
+	}
 
+	handler := server.Handler(myHandler)
+	if *sites != "" {
+		router := vhost.NewRouter()
+		router.Default = myHandler
+		for _, site := range strings.Split(*sites, ",") {
+			host, dir, found := strings.Cut(site, "=")
+			if !found {
+				log.Fatalf("Invalid site, expected host=directory: %s", site)
+			}
+			if err := router.Add(host, server.FileServer(dir)); err != nil {
+				log.Fatalf("Error configuring sites: %v", err)
+			}
+		}
+		handler = router.Handle
+	}
+	if *htpasswd != "" {
+		users, err := auth.LoadHtpasswd(*htpasswd)
+		if err != nil {
+			log.Fatalf("Error loading htpasswd: %v", err)
+		}
+		handler = server.Chain(handler, auth.Basic(*authRealm, users.Verify))
+	}
+	// Preflights carry no credentials, so CORS goes outside of auth.
+	if *corsOrigins != "" {
+		c, err := cors.New(cors.Options{
+			AllowedOrigins:   strings.Split(*corsOrigins, ","),
+			AllowedMethods:   []string{"GET", "HEAD", "POST", "PUT", "DELETE"},
+			AllowedHeaders:   []string{"*"},
+			AllowCredentials: *corsCreds,
+			MaxAge:           10 * time.Minute,
+		})
+		if err != nil {
+			log.Fatalf("Error configuring CORS: %v", err)
+		}
+		handler = server.Chain(handler, c.Middleware)
+	}
+	if *rateLimit > 0 {
+		key, ok := ratelimit.ParseKey(*rateKey)
+		if !ok {
+			log.Fatalf("Unknown rate limit key: %s", *rateKey)
+		}
+		var limiter ratelimit.Limiter
+		switch *rateAlgo {
+		case "token-bucket":
+			limiter = ratelimit.NewTokenBucket(float64(*rateLimit)/rateWindow.Seconds(), *rateLimit)
+		case "sliding-window":
+			limiter = ratelimit.NewSlidingWindow(*rateLimit, *rateWindow)
+		default:
+			log.Fatalf("Unknown rate limiting algorithm: %s", *rateAlgo)
+		}
+		handler = server.Chain(handler, ratelimit.Middleware(limiter, key))
+	}
+	if *accessLog != "" {
+		format, err := accesslog.ParseFormat(*logFormat)
+		if err != nil {
+			log.Fatalf("Error configuring access log: %v", err)
+		}
+		out := io.Writer(os.Stdout)
+		if *accessLog != "-" {
+			f, err := accesslog.OpenRotatingFile(*accessLog, *logMaxSize<<20, *logBackups)
+			if err != nil {
+				log.Fatalf("Error opening access log: %v", err)
+			}
+			defer f.Close()
+			out = f
+		}
+		handler = server.Chain(handler, accesslog.New(out, format).Middleware)
+	}
+
+	opts := []server.Option{
+		server.WithWriteTimeout(*writeTimeout),
+		server.WithConnLimits(server.ConnLimits{
+			MaxConnections: *maxConns,
+			MaxPerIP:       *maxConnsIP,
+			Queue:          *queueConns,
+		}),
+	}
+	if *proxyProto != "" {
+		trusted, err := server.ParsePrefixes(*proxyProto)
+		if err != nil {
+			log.Fatalf("Error configuring PROXY protocol: %v", err)
+		}
+		opts = append(opts, server.WithProxyProtocol(trusted))
+	}
+	if *metricsPath != "" {
+		registry = metrics.NewRegistry()
+		opts = append(opts, server.WithMetrics(server.NewMetrics(registry)))
 	}
-	defer server.Close()
-	log.Println("Server started on port", port)
 
+	listeners, err := listen()
+	if err != nil {
+		log.Fatalf("Error starting server: %v", err)
+	}
+	var servers []*server.Server
+	for _, l := range listeners {
+		servers = append(servers, server.ServeListener(l, handler, opts...))
+		log.Println("Server listening on", l.Addr())
+	}
+	if err := server.NotifyReady(); err != nil {
+		log.Printf("Error notifying the previous process: %v", err)
+	}
+
+	// SIGHUP and SIGUSR2 restart into a new process serving the same
+	// sockets, e.g. after the binary was upgraded.
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGUSR2)
+	for sig := range sigChan {
+		if sig != syscall.SIGHUP && sig != syscall.SIGUSR2 {
+			break
+		}
+		pid, err := server.Restart(listeners, *drainTimeout)
+		if err != nil {
+			log.Printf("Error restarting, still serving: %v", err)
+			continue
+		}
+		log.Println("Restarted as process", pid)
+		break
+	}
+	signal.Stop(sigChan)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *drainTimeout)
+	defer cancel()
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("Error draining connections: %v", err)
+		}
+	}
 	log.Println("Server gracefully stopped")
 }
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/danielNemeth19/http-protocol/internal/cors"
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/jsonhttp"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteErrorPageBehindCORS(t *testing.T) {
+	c, err := cors.New(cors.Options{AllowedOrigins: []string{"https://a.example"}})
+	require.NoError(t, err)
+	h := c.Middleware(func(w *response.Writer, req *request.Request) {
+		writeErrorPage(w, req, response.StatusNotFound, "<h1>Not Found</h1>")
+	})
+
+	for _, accept := range []string{"application/json", "text/html"} {
+		req := &request.Request{
+			RequestLine: request.RequestLine{Method: "GET", RequestTarget: "/missing", HttpVersion: "1.1"},
+			Headers:     headers.Headers{"origin": "https://a.example", "accept": accept},
+		}
+		var buf bytes.Buffer
+		h(&response.Writer{Writer: &buf}, req)
+		resp, err := http.ReadResponse(bufio.NewReader(&buf), nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, accept)
+		assert.Equal(t, "https://a.example", resp.Header.Get("Access-Control-Allow-Origin"), accept)
+		require.Len(t, resp.Header.Values("Vary"), 1, accept)
+		vary := strings.Split(resp.Header.Get("Vary"), ", ")
+		assert.ElementsMatch(t, []string{"Origin", "Accept"}, vary, accept)
+		if accept == "application/json" {
+			assert.Equal(t, jsonhttp.ProblemContentType, resp.Header.Get("Content-Type"))
+		}
+	}
+}
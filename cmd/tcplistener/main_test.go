@@ -7,6 +7,7 @@ import (
 	"testing/fstest"
 
 	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -81,3 +82,24 @@ func TestParsingHTTPPost(t *testing.T) {
 	assert.Equal(t, request.RequestLine.RequestTarget, "/coffee")
 	assert.Equal(t, request.RequestLine.HttpVersion, "1.1")
 }
+
+func TestParsingHTTPResponse(t *testing.T) {
+	message := HTTPMessage{
+		StartLine: "HTTP/1.1 201 Created",
+		FieldLines: []string{
+			"Content-Type: application/json",
+			"Content-Length: 23",
+		},
+		Body: "{\"flavor\": \"dark mode\"}",
+	}
+	stream := message.httpMessageAsReadCloser()
+	resp, err := response.ResponseFromReader(stream)
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, resp.StatusLine.HttpVersion, "1.1")
+	assert.Equal(t, resp.StatusLine.StatusCode, response.StatusCode(201))
+	assert.Equal(t, resp.StatusLine.ReasonPhrase, "Created")
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, string(body), message.Body)
+}
@@ -0,0 +1,163 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/danielNemeth19/http-protocol/internal/server"
+)
+
+type Format int
+
+const (
+	// CommonFormat is the NCSA Common Log Format:
+	// host ident user [time] "request" status bytes
+	CommonFormat Format = iota
+	// CombinedFormat is CommonFormat followed by "referer" "user-agent".
+	CombinedFormat
+	// JSONFormat writes one JSON object per line, including the duration.
+	JSONFormat
+)
+
+// ParseFormat returns the Format named "common", "combined" or "json".
+func ParseFormat(s string) (Format, error) {
+	switch s {
+	case "common":
+		return CommonFormat, nil
+	case "combined":
+		return CombinedFormat, nil
+	case "json":
+		return JSONFormat, nil
+	}
+	return 0, fmt.Errorf("Unknown access log format: %s", s)
+}
+
+// clfTimeFormat is the timestamp layout of the Common Log Format.
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// Entry is a single served request.
+type Entry struct {
+	Time       time.Time     `json:"time"`
+	RemoteAddr string        `json:"remote_addr"`
+	Method     string        `json:"method"`
+	Target     string        `json:"target"`
+	Proto      string        `json:"proto"`
+	Status     int           `json:"status"`
+	Bytes      int64         `json:"bytes"`
+	Duration   time.Duration `json:"-"`
+	Referer    string        `json:"referer,omitempty"`
+	UserAgent  string        `json:"user_agent,omitempty"`
+}
+
+// Logger writes an Entry per request to Out.
+type Logger struct {
+	Out    io.Writer
+	Format Format
+
+	mu sync.Mutex
+}
+
+// New returns a Logger writing entries in format to out.
+func New(out io.Writer, format Format) *Logger {
+	return &Logger{Out: out, Format: format}
+}
+
+// Middleware returns a server.Middleware logging every request once its
+// handler returned.
+func (l *Logger) Middleware(next server.Handler) server.Handler {
+	return func(w *response.Writer, req *request.Request) {
+		start := time.Now()
+		stats := response.NewStatsWriter(w)
+		// Handlers such as StripPrefix rewrite the request line.
+		line := req.RequestLine
+		next(w, req)
+		l.Log(&Entry{
+			Time:       start,
+			RemoteAddr: req.RemoteAddr,
+			Method:     line.Method,
+			Target:     line.RequestTarget,
+			Proto:      "HTTP/" + line.HttpVersion,
+			Status:     int(stats.Status()),
+			Bytes:      stats.BytesWritten(),
+			Duration:   time.Since(start),
+			Referer:    req.Headers.Get("referer"),
+			UserAgent:  req.Headers.Get("user-agent"),
+		})
+	}
+}
+
+// Log writes e in the Logger's format.
+func (l *Logger) Log(e *Entry) error {
+	var line []byte
+	switch l.Format {
+	case JSONFormat:
+		data, err := json.Marshal(struct {
+			*Entry
+			DurationMs float64 `json:"duration_ms"`
+		}{e, float64(e.Duration.Microseconds()) / 1000})
+		if err != nil {
+			return err
+		}
+		line = append(data, '\n')
+	default:
+		line = []byte(formatCommon(e, l.Format == CombinedFormat))
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err := l.Out.Write(line)
+	return err
+}
+
+func formatCommon(e *Entry, combined bool) string {
+	host := e.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	bytes := "-"
+	if e.Bytes > 0 {
+		bytes = strconv.FormatInt(e.Bytes, 10)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s - - [%s] \"%s %s %s\" %d %s",
+		orDash(host), e.Time.Format(clfTimeFormat),
+		escape(e.Method), escape(e.Target), escape(e.Proto), e.Status, bytes)
+	if combined {
+		fmt.Fprintf(&b, " \"%s\" \"%s\"", orDash(escape(e.Referer)), orDash(escape(e.UserAgent)))
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// escape keeps client-controlled values from breaking the quoted fields
+// or forging log lines.
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, "\\x%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,102 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/danielNemeth19/http-protocol/internal/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRequest() *request.Request {
+	return &request.Request{
+		RequestLine: request.RequestLine{Method: "GET", RequestTarget: "/api/items?x=1", HttpVersion: "1.1"},
+		Headers: headers.Headers{
+			"host":       "localhost",
+			"user-agent": `curl/8.0 "quoted"`,
+			"referer":    "http://example.com/",
+		},
+		RemoteAddr: "192.0.2.10:50000",
+	}
+}
+
+func hello(w *response.Writer, req *request.Request) {
+	w.WriteStatusLine(response.StatusOK)
+	w.WriteHeaders(response.GetDefaultHeaders(5))
+	w.WriteBody([]byte("hello"))
+}
+
+func TestMiddlewareCommonFormats(t *testing.T) {
+	// Test: Common Log Format
+	var out bytes.Buffer
+	h := New(&out, CommonFormat).Middleware(hello)
+	h(&response.Writer{Writer: &bytes.Buffer{}}, newRequest())
+	assert.Regexp(t, `^192\.0\.2\.10 - - \[\d\d/\w{3}/\d{4}:\d\d:\d\d:\d\d [+-]\d{4}\] "GET /api/items\?x=1 HTTP/1\.1" 200 5\n$`, out.String())
+
+	// Test: Combined Log Format escapes quotes
+	out.Reset()
+	h = New(&out, CombinedFormat).Middleware(hello)
+	h(&response.Writer{Writer: &bytes.Buffer{}}, newRequest())
+	assert.Contains(t, out.String(), `200 5 "http://example.com/" "curl/8.0 \"quoted\""`+"\n")
+
+	// Test: Errors written with HandlerError are recorded, the original
+	// target is logged after StripPrefix
+	out.Reset()
+	h = server.Chain(server.StripPrefix("/api", func(w *response.Writer, req *request.Request) {
+		errH := server.HandlerError{Code: response.StatusNotFound, Message: "Not found\n"}
+		errH.WriteError(w.Writer)
+	}), New(&out, CommonFormat).Middleware)
+	h(&response.Writer{Writer: &bytes.Buffer{}}, newRequest())
+	assert.Contains(t, out.String(), `"GET /api/items?x=1 HTTP/1.1" 404 10`)
+}
+
+func TestMiddlewareJSON(t *testing.T) {
+	var out bytes.Buffer
+	h := New(&out, JSONFormat).Middleware(func(w *response.Writer, req *request.Request) {
+		time.Sleep(2 * time.Millisecond)
+		w.WriteStatusLine(response.StatusNoContent)
+		w.WriteHeaders(headers.NewHeaders())
+	})
+	h(&response.Writer{Writer: &bytes.Buffer{}}, newRequest())
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, "192.0.2.10:50000", entry["remote_addr"])
+	assert.Equal(t, "GET", entry["method"])
+	assert.Equal(t, "/api/items?x=1", entry["target"])
+	assert.Equal(t, "HTTP/1.1", entry["proto"])
+	assert.Equal(t, float64(204), entry["status"])
+	assert.Equal(t, float64(0), entry["bytes"])
+	assert.Equal(t, `curl/8.0 "quoted"`, entry["user_agent"])
+	assert.GreaterOrEqual(t, entry["duration_ms"], float64(2))
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	f, err := OpenRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+	defer f.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	read := func(name string) string {
+		data, err := os.ReadFile(name)
+		require.NoError(t, err)
+		return string(data)
+	}
+	assert.Equal(t, "fourth\n", read(path))
+	assert.Equal(t, "third\n", read(path+".1"))
+	assert.Equal(t, "second\n", read(path+".2"))
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
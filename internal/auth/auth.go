@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/danielNemeth19/http-protocol/internal/response"
+)
+
+type subjectKey struct{}
+
+// WithSubject returns a copy of ctx carrying the authenticated subject: a
+// user name, token owner or key id.
+func WithSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, subjectKey{}, subject)
+}
+
+// Subject returns the subject the auth middlewares stored in ctx.
+func Subject(ctx context.Context) (string, bool) {
+	subject, ok := ctx.Value(subjectKey{}).(string)
+	return subject, ok
+}
+
+// unauthorized answers with 401 and the WWW-Authenticate challenge.
+func unauthorized(w *response.Writer, challenge string) {
+	msg := "Unauthorized\n"
+	w.WriteStatusLine(response.StatusUnauthorized)
+	h := response.GetDefaultHeaders(len(msg))
+	h.Set("WWW-Authenticate", challenge)
+	w.WriteHeaders(h)
+	w.WriteBody([]byte(msg))
+}
+
+// challenge builds a challenge for scheme with quoted auth-params, given
+// as name/value pairs.
+func challenge(scheme string, params ...string) string {
+	var b strings.Builder
+	b.WriteString(scheme)
+	for i := 0; i+1 < len(params); i += 2 {
+		if i == 0 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteString(", ")
+		}
+		b.WriteString(params[i] + "=" + strconv.Quote(params[i+1]))
+	}
+	return b.String()
+}
+
+// credentials splits an Authorization value into its scheme, compared
+// case-insensitively, and the rest.
+func credentials(authorization, scheme string) (string, bool) {
+	s, rest, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(s, scheme) {
+		return "", false
+	}
+	return strings.TrimSpace(rest), true
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/danielNemeth19/http-protocol/internal/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// whoami answers with the authenticated subject.
+func whoami(w *response.Writer, req *request.Request) {
+	subject, _ := Subject(req.Context())
+	w.WriteStatusLine(response.StatusOK)
+	w.WriteHeaders(response.GetDefaultHeaders(len(subject)))
+	w.WriteBody([]byte(subject))
+}
+
+func serve(t *testing.T, h server.Handler, fields headers.Headers, body string) (*http.Response, string) {
+	req := &request.Request{
+		RequestLine: request.RequestLine{Method: "POST", RequestTarget: "/items?x=1", HttpVersion: "1.1"},
+		Headers:     fields,
+		Body:        []byte(body),
+	}
+	var buf bytes.Buffer
+	h(&response.Writer{Writer: &buf}, req)
+	resp, err := http.ReadResponse(bufio.NewReader(&buf), nil)
+	require.NoError(t, err)
+	var b strings.Builder
+	_, err = bufio.NewReader(resp.Body).WriteTo(&b)
+	require.NoError(t, err)
+	return resp, b.String()
+}
+
+func basicAuth(user, password string) headers.Headers {
+	return headers.Headers{"authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))}
+}
+
+func TestBasic(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	htpasswd := "# users\n" +
+		"alice:" + strings.Replace(string(hash), "$2a$", "$2y$", 1) + "\n" +
+		"\n" +
+		"bob:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n" // password
+	users, err := ParseHtpasswd(strings.NewReader(htpasswd))
+	require.NoError(t, err)
+	h := Basic("admin area", users.Verify)(whoami)
+
+	resp, body := serve(t, h, basicAuth("alice", "s3cret"), "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "alice", body)
+	resp, body = serve(t, h, basicAuth("bob", "password"), "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "bob", body)
+
+	for _, fields := range []headers.Headers{
+		basicAuth("alice", "wrong"),
+		basicAuth("mallory", "s3cret"),
+		{"authorization": "Basic !!!"},
+		headers.NewHeaders(),
+	} {
+		resp, _ = serve(t, h, fields, "")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Equal(t, `Basic realm="admin area", charset="UTF-8"`, resp.Header.Get("WWW-Authenticate"))
+	}
+
+	_, err = ParseHtpasswd(strings.NewReader("carol:$apr1$salt$hash\n"))
+	assert.Error(t, err)
+}
+
+func TestBearer(t *testing.T) {
+	h := Bearer("api", StaticTokens{"token-1": "service-a"})(whoami)
+
+	resp, body := serve(t, h, headers.Headers{"authorization": "Bearer token-1"}, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "service-a", body)
+
+	resp, _ = serve(t, h, headers.NewHeaders(), "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, `Bearer realm="api"`, resp.Header.Get("WWW-Authenticate"))
+
+	resp, _ = serve(t, h, headers.Headers{"authorization": "Bearer token-2"}, "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, `Bearer realm="api", error="invalid_token", error_description="Invalid token"`, resp.Header.Get("WWW-Authenticate"))
+
+	// Test: Pluggable verifier sees the request context
+	verifier := VerifierFunc(func(ctx context.Context, token string) (string, error) {
+		if token != "jwt" {
+			return "", errors.New("Expired")
+		}
+		return "user-42", nil
+	})
+	resp, body = serve(t, Bearer("api", verifier)(whoami), headers.Headers{"authorization": "bearer jwt"}, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "user-42", body)
+
+	// Test: Verifier errors are not shown to the client
+	verifier = VerifierFunc(func(ctx context.Context, token string) (string, error) {
+		return "", errors.New("Introspection at 10.0.0.5 failed")
+	})
+	resp, _ = serve(t, Bearer("api", verifier)(whoami), headers.Headers{"authorization": "Bearer jwt"}, "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, `Bearer realm="api", error="invalid_token", error_description="Invalid token"`, resp.Header.Get("WWW-Authenticate"))
+}
+
+func TestHMAC(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	key := []byte("shared secret")
+	c := &HMAC{
+		Realm:   "api",
+		Key:     func(id string) ([]byte, bool) { return key, id == "client1" },
+		Headers: []string{"Date"},
+		MaxSkew: 5 * time.Minute,
+		now:     func() time.Time { return now },
+	}
+	h := c.Middleware(whoami)
+	signed := func(date, body string, signedHeaders ...string) headers.Headers {
+		fields := headers.Headers{"host": "localhost", "date": date, "content-type": "text/plain"}
+		fields["authorization"] = Sign("client1", key, "POST", "/items?x=1", fields, signedHeaders, []byte(body))
+		return fields
+	}
+	date := now.Format(response.TimeFormat)
+
+	resp, body := serve(t, h, signed(date, "payload", "host", "date"), "payload")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "client1", body)
+
+	for name, fields := range map[string]headers.Headers{
+		"tampered body":  signed(date, "payload", "host", "date"),
+		"date unsigned":  signed(date, "tampered", "host"),
+		"stale date":     signed(now.Add(-time.Hour).Format(response.TimeFormat), "tampered", "date"),
+		"no credentials": headers.NewHeaders(),
+	} {
+		resp, _ = serve(t, h, fields, "tampered")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, name)
+		assert.Equal(t, `HMAC-SHA256 realm="api", headers="date"`, resp.Header.Get("WWW-Authenticate"), name)
+	}
+
+	fields := signed(date, "payload", "date")
+	fields["content-type"] = "application/json"
+	resp, _ = serve(t, h, fields, "payload")
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "unsigned headers may change")
+	fields["date"] = now.Add(time.Second).Format(response.TimeFormat)
+	resp, _ = serve(t, h, fields, "payload")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "signed headers may not")
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/danielNemeth19/http-protocol/internal/server"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Htpasswd holds the users of an htpasswd file. Passwords must be hashed
+// with bcrypt ($2y$, as written by htpasswd -B) or SHA-1 ({SHA}).
+type Htpasswd struct {
+	users map[string]string
+}
+
+// dummyHash returns the hash compared against for unknown users, so that
+// the time a failed login takes does not reveal whether the user exists.
+// It is computed on first use rather than slowing down every program
+// importing the package.
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// LoadHtpasswd reads the htpasswd file at path.
+func LoadHtpasswd(path string) (*Htpasswd, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseHtpasswd(f)
+}
+
+// ParseHtpasswd reads user:hash lines; blank lines and # comments are
+// skipped.
+func ParseHtpasswd(r io.Reader) (*Htpasswd, error) {
+	h := &Htpasswd{users: make(map[string]string)}
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		user, hash, found := strings.Cut(line, ":")
+		if !found || user == "" {
+			return nil, fmt.Errorf("Invalid htpasswd line %d", lineNo)
+		}
+		if !isBcrypt(hash) && !strings.HasPrefix(hash, "{SHA}") {
+			return nil, fmt.Errorf("Unsupported password hash for user %s on line %d", user, lineNo)
+		}
+		h.users[user] = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func isBcrypt(hash string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify reports whether password is the password of user.
+func (h *Htpasswd) Verify(user, password string) bool {
+	hash, ok := h.users[user]
+	if !ok {
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return false
+	}
+	if sha, found := strings.CutPrefix(hash, "{SHA}"); found {
+		sum := sha1.Sum([]byte(password))
+		computed := base64.StdEncoding.EncodeToString(sum[:])
+		return subtle.ConstantTimeCompare([]byte(computed), []byte(sha)) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// Basic returns a server.Middleware requiring HTTP Basic authentication
+// (RFC 7617) with a user and password accepted by verify, e.g.
+// Htpasswd.Verify. The user name is stored as the request's Subject.
+func Basic(realm string, verify func(user, password string) bool) server.Middleware {
+	challenge := challenge("Basic", "realm", realm, "charset", "UTF-8")
+	return func(next server.Handler) server.Handler {
+		return func(w *response.Writer, req *request.Request) {
+			user, password, ok := basicCredentials(req.Headers.Get("authorization"))
+			if !ok || !verify(user, password) {
+				unauthorized(w, challenge)
+				return
+			}
+			next(w, req.WithContext(WithSubject(req.Context(), user)))
+		}
+	}
+}
+
+func basicCredentials(authorization string) (user, password string, ok bool) {
+	encoded, ok := credentials(authorization, "Basic")
+	if !ok {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", false
+	}
+	return strings.Cut(string(decoded), ":")
+}
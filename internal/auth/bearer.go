@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"log"
+
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/danielNemeth19/http-protocol/internal/server"
+)
+
+// TokenVerifier checks a bearer token and returns the subject it was
+// issued to. It is where JWT validation or a call to an introspection
+// endpoint plugs in.
+type TokenVerifier interface {
+	VerifyToken(ctx context.Context, token string) (subject string, err error)
+}
+
+// VerifierFunc adapts a function to a TokenVerifier.
+type VerifierFunc func(ctx context.Context, token string) (string, error)
+
+func (f VerifierFunc) VerifyToken(ctx context.Context, token string) (string, error) {
+	return f(ctx, token)
+}
+
+var ErrInvalidToken = errors.New("Invalid token")
+
+// StaticTokens is a TokenVerifier for a fixed set of tokens, mapped to
+// their subjects. Tokens are compared in constant time.
+type StaticTokens map[string]string
+
+func (s StaticTokens) VerifyToken(ctx context.Context, token string) (string, error) {
+	// Comparing digests keeps the comparison constant-time even for
+	// tokens of different lengths.
+	digest := sha256.Sum256([]byte(token))
+	subject, found := "", false
+	for known, sub := range s {
+		knownDigest := sha256.Sum256([]byte(known))
+		if subtle.ConstantTimeCompare(digest[:], knownDigest[:]) == 1 {
+			subject, found = sub, true
+		}
+	}
+	if !found {
+		return "", ErrInvalidToken
+	}
+	return subject, nil
+}
+
+// Bearer returns a server.Middleware requiring a bearer token (RFC 6750)
+// accepted by verifier. The token's subject is stored as the request's
+// Subject.
+func Bearer(realm string, verifier TokenVerifier) server.Middleware {
+	return func(next server.Handler) server.Handler {
+		return func(w *response.Writer, req *request.Request) {
+			token, ok := credentials(req.Headers.Get("authorization"), "Bearer")
+			if !ok || token == "" {
+				unauthorized(w, challenge("Bearer", "realm", realm))
+				return
+			}
+			subject, err := verifier.VerifyToken(req.Context(), token)
+			if err != nil {
+				// The verifier's error may describe its backend, it is
+				// logged rather than shown to the client.
+				if !errors.Is(err, ErrInvalidToken) {
+					log.Printf("Verifying bearer token failed: %v\n", err)
+				}
+				unauthorized(w, challenge("Bearer", "realm", realm,
+					"error", "invalid_token", "error_description", ErrInvalidToken.Error()))
+				return
+			}
+			next(w, req.WithContext(WithSubject(req.Context(), subject)))
+		}
+	}
+}
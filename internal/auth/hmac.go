@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/danielNemeth19/http-protocol/internal/server"
+)
+
+// HMACScheme is the authentication scheme of signed requests:
+//
+//	Authorization: HMAC-SHA256 keyId="client1", headers="host date", signature="<base64>"
+//
+// The signature is an HMAC-SHA256 over the string built by
+// SigningString.
+const HMACScheme = "HMAC-SHA256"
+
+// HMAC configures the verification of signed requests.
+type HMAC struct {
+	Realm string
+	// Key returns the secret of a key id.
+	Key func(keyID string) ([]byte, bool)
+	// Headers must be among the signed headers, e.g. "date" to bound
+	// replays together with MaxSkew.
+	Headers []string
+	// MaxSkew, if positive, rejects requests whose signed Date header is
+	// further than this from the server's clock.
+	MaxSkew time.Duration
+	now     func() time.Time
+}
+
+// SigningString returns what is signed for a request: the method, the
+// request target, each signed header as "name:value" in the given order
+// and the base64 SHA-256 digest of the body, separated by newlines.
+func SigningString(method, target string, h headers.Headers, signed []string, body []byte) string {
+	var b strings.Builder
+	b.WriteString(method + "\n")
+	b.WriteString(target + "\n")
+	for _, name := range signed {
+		name = strings.ToLower(name)
+		b.WriteString(name + ":" + strings.TrimSpace(h.Get(name)) + "\n")
+	}
+	digest := sha256.Sum256(body)
+	b.WriteString(base64.StdEncoding.EncodeToString(digest[:]))
+	return b.String()
+}
+
+// Sign returns the Authorization value for a request signed with key.
+// The headers named in signed must already be set in h.
+func Sign(keyID string, key []byte, method, target string, h headers.Headers, signed []string, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(SigningString(method, target, h, signed, body)))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return fmt.Sprintf(`%s keyId="%s", headers="%s", signature="%s"`,
+		HMACScheme, keyID, strings.ToLower(strings.Join(signed, " ")), signature)
+}
+
+// Middleware returns a server.Middleware requiring requests signed with
+// one of the keys. The key id is stored as the request's Subject.
+func (c *HMAC) Middleware(next server.Handler) server.Handler {
+	params := []string{"realm", c.Realm}
+	if len(c.Headers) > 0 {
+		params = append(params, "headers", strings.ToLower(strings.Join(c.Headers, " ")))
+	}
+	challenge := challenge(HMACScheme, params...)
+	return func(w *response.Writer, req *request.Request) {
+		keyID, err := c.verify(req)
+		if err != nil {
+			unauthorized(w, challenge)
+			return
+		}
+		next(w, req.WithContext(WithSubject(req.Context(), keyID)))
+	}
+}
+
+func (c *HMAC) verify(req *request.Request) (string, error) {
+	rest, ok := credentials(req.Headers.Get("authorization"), HMACScheme)
+	if !ok {
+		return "", fmt.Errorf("Missing %s credentials", HMACScheme)
+	}
+	params := parseParams(rest)
+	keyID, signature := params["keyid"], params["signature"]
+	signed := strings.Fields(params["headers"])
+	for _, required := range c.Headers {
+		if !slices.Contains(signed, strings.ToLower(required)) {
+			return "", fmt.Errorf("Header %s is not signed", required)
+		}
+	}
+	key, ok := c.Key(keyID)
+	if !ok {
+		return "", fmt.Errorf("Unknown key: %s", keyID)
+	}
+	got, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(SigningString(req.RequestLine.Method, req.RequestLine.RequestTarget, req.Headers, signed, req.Body)))
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return "", fmt.Errorf("Signature mismatch")
+	}
+	if c.MaxSkew > 0 {
+		if !slices.Contains(signed, "date") {
+			return "", fmt.Errorf("Date is not signed")
+		}
+		date, err := time.Parse(response.TimeFormat, req.Headers.Get("date"))
+		if err != nil {
+			return "", err
+		}
+		now := time.Now()
+		if c.now != nil {
+			now = c.now()
+		}
+		if skew := now.Sub(date); skew > c.MaxSkew || skew < -c.MaxSkew {
+			return "", fmt.Errorf("Date is out of range")
+		}
+	}
+	return keyID, nil
+}
+
+// parseParams parses comma-separated name="value" auth-params, with
+// lowercase names.
+func parseParams(s string) map[string]string {
+	params := make(map[string]string)
+	for _, param := range strings.Split(s, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if !found {
+			continue
+		}
+		params[strings.ToLower(name)] = strings.Trim(value, `"`)
+	}
+	return params
+}
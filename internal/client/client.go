@@ -0,0 +1,341 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+)
+
+const (
+	defaultDialTimeout    = 10 * time.Second
+	defaultIdleTimeout    = 90 * time.Second
+	defaultMaxIdlePerHost = 2
+)
+
+// idempotentMethods may be resent on a fresh connection when a pooled one
+// turns out to be closed by the server.
+var idempotentMethods = []string{"GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE"}
+
+// Request is an outgoing request. The Host field defaults to the host of
+// URL unless set in Headers.
+type Request struct {
+	Method  string
+	URL     *url.URL
+	Headers headers.Headers
+	Body    []byte
+}
+
+// NewRequest returns a Request for an absolute http or https URL.
+func NewRequest(method, rawURL string, body []byte) (*Request, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("Unsupported URL: %s", rawURL)
+	}
+	return &Request{Method: method, URL: u, Headers: headers.NewHeaders(), Body: body}, nil
+}
+
+// Client is an HTTP/1.1 client keeping idle connections for reuse. The
+// zero value is ready to use.
+type Client struct {
+	// DialTimeout bounds establishing a connection; 0 means 10 seconds.
+	DialTimeout time.Duration
+	// Timeout bounds a whole exchange, including reading the body;
+	// 0 means no limit besides the context.
+	Timeout time.Duration
+	// IdleTimeout is how long a pooled connection may stay unused;
+	// 0 means 90 seconds.
+	IdleTimeout time.Duration
+	// MaxIdlePerHost caps the pooled connections per host; 0 means 2.
+	MaxIdlePerHost int
+	// TLSConfig is used for https URLs.
+	TLSConfig *tls.Config
+	// Control, if set, is called with the resolved address of each new
+	// connection before it is made, as net.Dialer.Control; an error
+	// aborts the request.
+	Control func(network, address string, c syscall.RawConn) error
+
+	mu   sync.Mutex
+	idle map[string][]*persistConn
+}
+
+var DefaultClient = &Client{}
+
+type persistConn struct {
+	conn     net.Conn
+	br       *bufio.Reader
+	key      string
+	idleFrom time.Time
+}
+
+// Get issues a GET with DefaultClient.
+func Get(rawURL string) (*response.Response, error) {
+	return DefaultClient.Get(rawURL)
+}
+
+func (c *Client) Get(rawURL string) (*response.Response, error) {
+	req, err := NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(context.Background(), req)
+}
+
+// Do sends req and parses the response head. The caller must read and
+// close the response Body; a fully read body hands the connection back to
+// the pool.
+func (c *Client) Do(ctx context.Context, req *Request) (*response.Response, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		resp, err := c.do(ctx, req)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+		return resp, nil
+	}
+	return c.do(ctx, req)
+}
+
+func (c *Client) do(ctx context.Context, req *Request) (*response.Response, error) {
+	for {
+		pc, reused, err := c.getConn(ctx, req.URL)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, fmt.Errorf("%w: %v", ctxErr, err)
+			}
+			return nil, err
+		}
+		resp, err := c.roundTrip(ctx, pc, req)
+		if err == nil {
+			return resp, nil
+		}
+		pc.conn.Close()
+		if ctxErr := contextErr(ctx, err); ctxErr != nil {
+			return nil, fmt.Errorf("%w: %v", ctxErr, err)
+		}
+		// The server may have closed an idle connection just as we
+		// picked it up; retry those on a fresh connection.
+		if reused && slices.Contains(idempotentMethods, req.Method) {
+			continue
+		}
+		return nil, err
+	}
+}
+
+// contextErr returns the error of ctx if err was caused by ctx ending. The
+// connection deadline mirrors the context's, so the read may time out a
+// moment before ctx reports it is done.
+func contextErr(ctx context.Context, err error) error {
+	var netErr net.Error
+	if _, ok := ctx.Deadline(); ok && errors.As(err, &netErr) && netErr.Timeout() {
+		<-ctx.Done()
+	}
+	return ctx.Err()
+}
+
+func (c *Client) roundTrip(ctx context.Context, pc *persistConn, req *Request) (*response.Response, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		pc.conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		// Unblock any pending read or write.
+		pc.conn.SetDeadline(time.Unix(1, 0))
+	})
+	if err := writeRequest(pc.conn, req); err != nil {
+		stop()
+		return nil, err
+	}
+	resp, err := response.ResponseFromReaderFor(pc.br, req.Method)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	resp.Body = &body{
+		ReadCloser: resp.Body,
+		client:     c,
+		pc:         pc,
+		reusable:   !resp.Close && req.Headers.Get("connection") != "close",
+		stop:       stop,
+	}
+	return resp, nil
+}
+
+func writeRequest(w io.Writer, req *Request) error {
+	target := req.URL.RequestURI()
+	var b strings.Builder
+	b.WriteString(req.Method + " " + target + " HTTP/1.1\r\n")
+	host := req.Headers.Get("host")
+	if host == "" {
+		host = req.URL.Host
+	}
+	b.WriteString("Host: " + host + "\r\n")
+	for k, v := range req.Headers {
+		switch strings.ToLower(k) {
+		case "host", "content-length", "transfer-encoding":
+			continue
+		}
+		b.WriteString(k + ": " + v + "\r\n")
+	}
+	if len(req.Body) > 0 || (req.Method != "GET" && req.Method != "HEAD") {
+		b.WriteString("Content-Length: " + strconv.Itoa(len(req.Body)) + "\r\n")
+	}
+	b.WriteString("\r\n")
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return err
+	}
+	_, err := w.Write(req.Body)
+	return err
+}
+
+func (c *Client) getConn(ctx context.Context, u *url.URL) (*persistConn, bool, error) {
+	key := u.Scheme + "://" + hostPort(u)
+	if pc := c.popIdle(key); pc != nil {
+		return pc, true, nil
+	}
+	timeout := c.DialTimeout
+	if timeout == 0 {
+		timeout = defaultDialTimeout
+	}
+	dialer := &net.Dialer{Timeout: timeout, Control: c.Control}
+	var conn net.Conn
+	var err error
+	if u.Scheme == "https" {
+		config := c.TLSConfig
+		if config == nil {
+			config = &tls.Config{}
+		}
+		td := &tls.Dialer{NetDialer: dialer, Config: config}
+		conn, err = td.DialContext(ctx, "tcp", hostPort(u))
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", hostPort(u))
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &persistConn{conn: conn, br: bufio.NewReader(conn), key: key}, false, nil
+}
+
+func (c *Client) popIdle(key string) *persistConn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	idleTimeout := c.IdleTimeout
+	if idleTimeout == 0 {
+		idleTimeout = defaultIdleTimeout
+	}
+	for conns := c.idle[key]; len(conns) > 0; conns = c.idle[key] {
+		pc := conns[len(conns)-1]
+		c.idle[key] = conns[:len(conns)-1]
+		if time.Since(pc.idleFrom) < idleTimeout {
+			return pc
+		}
+		pc.conn.Close()
+	}
+	return nil
+}
+
+func (c *Client) putIdle(pc *persistConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	maxIdle := c.MaxIdlePerHost
+	if maxIdle == 0 {
+		maxIdle = defaultMaxIdlePerHost
+	}
+	if c.idle == nil {
+		c.idle = make(map[string][]*persistConn)
+	}
+	if len(c.idle[pc.key]) >= maxIdle || pc.br.Buffered() > 0 {
+		pc.conn.Close()
+		return
+	}
+	pc.conn.SetDeadline(time.Time{})
+	pc.idleFrom = time.Now()
+	c.idle[pc.key] = append(c.idle[pc.key], pc)
+}
+
+// CloseIdleConnections closes every pooled connection.
+func (c *Client) CloseIdleConnections() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, conns := range c.idle {
+		for _, pc := range conns {
+			pc.conn.Close()
+		}
+		delete(c.idle, key)
+	}
+}
+
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+// body returns the connection to the pool once the response body has
+// been read to EOF, and closes it if the body is abandoned.
+type body struct {
+	io.ReadCloser
+	client   *Client
+	pc       *persistConn
+	reusable bool
+	stop     func() bool
+	eof      bool
+	closed   bool
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	if b.closed {
+		return 0, errors.New("Read on closed response body")
+	}
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.eof = true
+	}
+	return n, err
+}
+
+func (b *body) Close() error {
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	// A context that already fired has poisoned the deadline.
+	if b.stop() && b.eof && b.reusable {
+		b.client.putIdle(b.pc)
+		return nil
+	}
+	return b.pc.conn.Close()
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
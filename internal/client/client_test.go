@@ -0,0 +1,150 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readBody(t *testing.T, body io.ReadCloser) string {
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	return string(data)
+}
+
+func TestClientGet(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Path", r.URL.RequestURI())
+		w.Write([]byte("hello"))
+	}))
+	defer s.Close()
+
+	resp, err := (&Client{}).Get(s.URL + "/a?b=c")
+	require.NoError(t, err)
+	assert.Equal(t, "1.1", resp.StatusLine.HttpVersion)
+	assert.EqualValues(t, 200, resp.StatusLine.StatusCode)
+	assert.Equal(t, "OK", resp.StatusLine.ReasonPhrase)
+	assert.Equal(t, "/a?b=c", resp.Headers.Get("x-path"))
+	assert.EqualValues(t, 5, resp.ContentLength)
+	assert.Equal(t, "hello", readBody(t, resp.Body))
+}
+
+func TestClientPostBody(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte(r.Method + " " + r.Header.Get("Content-Type") + " " + string(body)))
+	}))
+	defer s.Close()
+
+	req, err := NewRequest("POST", s.URL, []byte(`{"a":1}`))
+	require.NoError(t, err)
+	req.Headers.Set("Content-Type", "application/json")
+	resp, err := (&Client{}).Do(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, `POST application/json {"a":1}`, readBody(t, resp.Body))
+}
+
+func TestClientChunkedWithTrailers(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Sum")
+		w.Write([]byte("part one, "))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("part two"))
+		w.Header().Set("X-Sum", "42")
+	}))
+	defer s.Close()
+
+	resp, err := (&Client{}).Get(s.URL)
+	require.NoError(t, err)
+	assert.EqualValues(t, -1, resp.ContentLength)
+	assert.Equal(t, "part one, part two", readBody(t, resp.Body))
+	assert.Equal(t, "42", resp.Trailers.Get("x-sum"))
+}
+
+func TestClientReusesConnections(t *testing.T) {
+	var conns atomic.Int32
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	s.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	s.Start()
+	defer s.Close()
+
+	c := &Client{}
+	defer c.CloseIdleConnections()
+	for range 3 {
+		resp, err := c.Get(s.URL)
+		require.NoError(t, err)
+		assert.Equal(t, "ok", readBody(t, resp.Body))
+	}
+	assert.EqualValues(t, 1, conns.Load())
+}
+
+func TestClientRetriesStalePooledConnection(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer s.Close()
+
+	c := &Client{}
+	resp, err := c.Get(s.URL)
+	require.NoError(t, err)
+	readBody(t, resp.Body)
+	s.CloseClientConnections()
+
+	resp, err = c.Get(s.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", readBody(t, resp.Body))
+}
+
+func TestClientCloseDelimitedBody(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		buf := make([]byte, 1024)
+		conn.Read(buf)
+		conn.Write([]byte("HTTP/1.1 200 Fine By Me\r\nContent-Type: text/plain\r\n\r\nuntil the end"))
+		conn.Close()
+	}()
+
+	resp, err := (&Client{}).Get("http://" + listener.Addr().String())
+	require.NoError(t, err)
+	assert.Equal(t, "Fine By Me", resp.StatusLine.ReasonPhrase)
+	assert.True(t, resp.Close)
+	assert.Equal(t, "until the end", readBody(t, resp.Body))
+}
+
+func TestClientTimeout(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer s.Close()
+
+	_, err := (&Client{Timeout: 20 * time.Millisecond}).Get(s.URL)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestNewRequestRejectsRelativeURL(t *testing.T) {
+	_, err := NewRequest("GET", "/relative", nil)
+	require.Error(t, err)
+}
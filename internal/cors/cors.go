@@ -0,0 +1,201 @@
+package cors
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/danielNemeth19/http-protocol/internal/server"
+)
+
+// defaultMethods are the CORS-safelisted methods, allowed when
+// Options.AllowedMethods is empty.
+var defaultMethods = []string{"GET", "HEAD", "POST"}
+
+// Options configure which cross-origin requests are allowed.
+type Options struct {
+	// AllowedOrigins are origins such as "https://app.example.com".
+	// "*" allows any origin and "https://*.example.com" any subdomain.
+	AllowedOrigins []string
+	// AllowOriginFunc, if set, is consulted for origins that
+	// AllowedOrigins does not match.
+	AllowOriginFunc func(origin string) bool
+	// AllowedMethods defaults to GET, HEAD and POST.
+	AllowedMethods []string
+	// AllowedHeaders are the request headers a client may send; "*"
+	// allows any.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers scripts may read.
+	ExposedHeaders []string
+	// AllowCredentials lets requests carry cookies and HTTP
+	// authentication. It cannot be combined with the "*" origin.
+	AllowCredentials bool
+	// MaxAge is how long a preflight result may be cached; 0 omits
+	// Access-Control-Max-Age.
+	MaxAge time.Duration
+}
+
+// CORS implements the server side of the Fetch standard's CORS protocol.
+type CORS struct {
+	opts    Options
+	anyOrig bool
+	methods []string
+	headers []string
+	anyHdr  bool
+}
+
+// New returns a CORS for opts. It fails if opts allow credentials from
+// any origin, which would let every site act on behalf of a logged in
+// user.
+func New(opts Options) (*CORS, error) {
+	c := &CORS{opts: opts, methods: opts.AllowedMethods}
+	if len(c.methods) == 0 {
+		c.methods = defaultMethods
+	}
+	c.anyOrig = slices.Contains(opts.AllowedOrigins, "*")
+	if c.anyOrig && opts.AllowCredentials {
+		return nil, errors.New("Credentials cannot be allowed for any origin")
+	}
+	for _, h := range opts.AllowedHeaders {
+		if h == "*" {
+			c.anyHdr = true
+			continue
+		}
+		c.headers = append(c.headers, strings.ToLower(h))
+	}
+	return c, nil
+}
+
+// Middleware answers preflight requests and adds the Access-Control-*
+// fields to the responses of next. Preflights from disallowed origins get
+// 403 Forbidden; other requests from them, like those without an Origin,
+// pass through without Access-Control-* fields, so browsers keep the
+// response from the script.
+func (c *CORS) Middleware(next server.Handler) server.Handler {
+	return func(w *response.Writer, req *request.Request) {
+		origin := req.Headers.Get("origin")
+		if origin == "" {
+			c.addVary(w, "Origin")
+			next(w, req)
+			return
+		}
+		preflight := req.RequestLine.Method == "OPTIONS" && req.Headers.Get("access-control-request-method") != ""
+		if !c.originAllowed(origin) {
+			if preflight {
+				forbidden(w, "Origin not allowed\n")
+				return
+			}
+			c.addVary(w, "Origin")
+			next(w, req)
+			return
+		}
+		if preflight {
+			c.preflight(w, req, origin)
+			return
+		}
+		c.addVary(w, "Origin")
+		w.AddHeader("Access-Control-Allow-Origin", c.allowOrigin(origin))
+		if c.opts.AllowCredentials {
+			w.AddHeader("Access-Control-Allow-Credentials", "true")
+		}
+		if len(c.opts.ExposedHeaders) > 0 {
+			w.AddHeader("Access-Control-Expose-Headers", strings.Join(c.opts.ExposedHeaders, ", "))
+		}
+		next(w, req)
+	}
+}
+
+func (c *CORS) preflight(w *response.Writer, req *request.Request, origin string) {
+	method := req.Headers.Get("access-control-request-method")
+	if !slices.Contains(c.methods, method) {
+		forbidden(w, "Method not allowed\n")
+		return
+	}
+	var requested []string
+	for _, h := range strings.Split(req.Headers.Get("access-control-request-headers"), ",") {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			requested = append(requested, h)
+		}
+	}
+	for _, h := range requested {
+		if !c.anyHdr && !slices.Contains(c.headers, h) {
+			forbidden(w, "Header not allowed: "+h+"\n")
+			return
+		}
+	}
+
+	h := headers.Headers{"Connection": "close"}
+	h.Set("Access-Control-Allow-Origin", c.allowOrigin(origin))
+	h.Set("Access-Control-Allow-Methods", strings.Join(c.methods, ", "))
+	if len(requested) > 0 {
+		// Echoing the requested headers also covers "*", which is
+		// not a wildcard for requests with credentials.
+		h.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+	}
+	if c.opts.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if c.opts.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.opts.MaxAge.Seconds())))
+	}
+	h.Set("Vary", "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+	w.WriteStatusLine(response.StatusNoContent)
+	w.WriteHeaders(h)
+}
+
+func (c *CORS) originAllowed(origin string) bool {
+	if c.anyOrig {
+		return true
+	}
+	for _, allowed := range c.opts.AllowedOrigins {
+		if strings.EqualFold(allowed, origin) || matchWildcard(allowed, origin) {
+			return true
+		}
+	}
+	return c.opts.AllowOriginFunc != nil && c.opts.AllowOriginFunc(origin)
+}
+
+// matchWildcard matches origin against a pattern like
+// "https://*.example.com", where * stands for one or more subdomain
+// labels.
+func matchWildcard(pattern, origin string) bool {
+	prefix, suffix, found := strings.Cut(strings.ToLower(pattern), "*")
+	if !found {
+		return false
+	}
+	origin = strings.ToLower(origin)
+	if len(origin) <= len(prefix)+len(suffix) {
+		return false
+	}
+	if !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+		return false
+	}
+	return !strings.ContainsAny(origin[len(prefix):len(origin)-len(suffix)], "/:@")
+}
+
+// allowOrigin returns the value of Access-Control-Allow-Origin.
+func (c *CORS) allowOrigin(origin string) string {
+	if c.anyOrig {
+		return "*"
+	}
+	return origin
+}
+
+// addVary adds field to Vary unless the response is the same for every
+// origin.
+func (c *CORS) addVary(w *response.Writer, field string) {
+	if c.anyOrig {
+		return
+	}
+	w.AddHeader("Vary", field)
+}
+
+func forbidden(w *response.Writer, msg string) {
+	errH := server.HandlerError{Code: response.StatusForbidden, Message: msg}
+	errH.WriteError(w.Writer)
+}
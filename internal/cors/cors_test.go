@@ -0,0 +1,132 @@
+package cors
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ok(w *response.Writer, req *request.Request) {
+	w.WriteStatusLine(response.StatusOK)
+	h := response.GetDefaultHeaders(2)
+	h.Set("Vary", "Accept")
+	w.WriteHeaders(h)
+	w.WriteBody([]byte("ok"))
+}
+
+func serve(t *testing.T, c *CORS, method string, fields headers.Headers) *http.Response {
+	req := &request.Request{
+		RequestLine: request.RequestLine{Method: method, RequestTarget: "/api", HttpVersion: "1.1"},
+		Headers:     fields,
+	}
+	var buf bytes.Buffer
+	c.Middleware(ok)(&response.Writer{Writer: &buf}, req)
+	resp, err := http.ReadResponse(bufio.NewReader(&buf), &http.Request{Method: method})
+	require.NoError(t, err)
+	return resp
+}
+
+func TestPreflight(t *testing.T) {
+	c, err := New(Options{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods:   []string{"GET", "PUT"},
+		AllowedHeaders:   []string{"Content-Type", "X-Request-Id"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+	require.NoError(t, err)
+
+	resp := serve(t, c, "OPTIONS", headers.Headers{
+		"origin":                         "https://app.example.com",
+		"access-control-request-method":  "PUT",
+		"access-control-request-headers": "content-type, x-request-id",
+	})
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "https://app.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, PUT", resp.Header.Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "content-type, x-request-id", resp.Header.Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "600", resp.Header.Get("Access-Control-Max-Age"))
+	assert.Contains(t, resp.Header.Get("Vary"), "Origin")
+
+	// Test: Wildcard subdomain
+	resp = serve(t, c, "OPTIONS", headers.Headers{
+		"origin":                        "https://a.b.example.org",
+		"access-control-request-method": "GET",
+	})
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "https://a.b.example.org", resp.Header.Get("Access-Control-Allow-Origin"))
+
+	// Test: Disallowed origin, method and header
+	for _, fields := range []headers.Headers{
+		{"origin": "https://evil.com", "access-control-request-method": "GET"},
+		{"origin": "https://example.org", "access-control-request-method": "GET"},
+		{"origin": "https://app.example.com", "access-control-request-method": "DELETE"},
+		{"origin": "https://app.example.com", "access-control-request-method": "GET", "access-control-request-headers": "x-secret"},
+	} {
+		resp = serve(t, c, "OPTIONS", fields)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, fields)
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestActualRequest(t *testing.T) {
+	c, err := New(Options{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		ExposedHeaders:   []string{"X-Total-Count"},
+		AllowCredentials: true,
+	})
+	require.NoError(t, err)
+
+	resp := serve(t, c, "GET", headers.Headers{"origin": "https://app.example.com"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "https://app.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "X-Total-Count", resp.Header.Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, "Accept, Origin", resp.Header.Get("Vary"))
+
+	// Test: Disallowed origins get the response without CORS fields
+	resp = serve(t, c, "GET", headers.Headers{"origin": "https://evil.com"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, resp.Header.Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "Accept, Origin", resp.Header.Get("Vary"))
+
+	// Test: Same-origin requests pass through
+	resp = serve(t, c, "GET", headers.NewHeaders())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Accept, Origin", resp.Header.Get("Vary"))
+
+	// Test: OPTIONS without Access-Control-Request-Method is not a preflight
+	resp = serve(t, c, "OPTIONS", headers.Headers{"origin": "https://app.example.com"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestAnyOrigin(t *testing.T) {
+	c, err := New(Options{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"*"}})
+	require.NoError(t, err)
+	resp := serve(t, c, "GET", headers.Headers{"origin": "https://anywhere.test"})
+	assert.Equal(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Accept", resp.Header.Get("Vary"))
+
+	resp = serve(t, c, "OPTIONS", headers.Headers{
+		"origin":                         "https://anywhere.test",
+		"access-control-request-method":  "POST",
+		"access-control-request-headers": "x-anything",
+	})
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "x-anything", resp.Header.Get("Access-Control-Allow-Headers"))
+
+	// Test: Credentials cannot be allowed for any origin
+	_, err = New(Options{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+	assert.Error(t, err)
+}
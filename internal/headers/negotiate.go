@@ -0,0 +1,154 @@
+package headers
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Preference is one member of a weighted list such as Accept or
+// Accept-Language.
+type Preference struct {
+	Value  string
+	Q      float64
+	Params map[string]string
+}
+
+// ParsePreferences parses a comma-separated list of values with optional
+// parameters and q-weights. The result is sorted by descending weight;
+// members with equal weight keep their order. Members with an invalid
+// q-value are dropped.
+func ParsePreferences(s string) []Preference {
+	var prefs []Preference
+	for _, member := range strings.Split(s, ",") {
+		parts := strings.Split(member, ";")
+		value := strings.ToLower(strings.TrimSpace(parts[0]))
+		if value == "" {
+			continue
+		}
+		pref := Preference{Value: value, Q: 1}
+		valid := true
+		for _, param := range parts[1:] {
+			k, v, _ := strings.Cut(param, "=")
+			k = strings.ToLower(strings.TrimSpace(k))
+			v = strings.Trim(strings.TrimSpace(v), `"`)
+			if k == "q" {
+				q, err := strconv.ParseFloat(v, 64)
+				if err != nil || q < 0 || q > 1 {
+					valid = false
+					break
+				}
+				pref.Q = q
+				continue
+			}
+			if pref.Params == nil {
+				pref.Params = make(map[string]string)
+			}
+			pref.Params[k] = v
+		}
+		if valid {
+			prefs = append(prefs, pref)
+		}
+	}
+	slices.SortStableFunc(prefs, func(a, b Preference) int {
+		switch {
+		case a.Q > b.Q:
+			return -1
+		case a.Q < b.Q:
+			return 1
+		}
+		return 0
+	})
+	return prefs
+}
+
+// NegotiateContentType picks the offer that best matches the Accept
+// header. Without an Accept header the first offer is returned; if no offer
+// is acceptable the result is "".
+func (h Headers) NegotiateContentType(offers []string) string {
+	return negotiate(h.Get("accept"), offers, matchMediaRange)
+}
+
+// NegotiateLanguage picks the offer that best matches Accept-Language,
+// using the basic filtering of RFC 4647 ("en" matches "en-US").
+func (h Headers) NegotiateLanguage(offers []string) string {
+	return negotiate(h.Get("accept-language"), offers, matchLanguageRange)
+}
+
+// NegotiateCharset picks the offer that best matches Accept-Charset.
+func (h Headers) NegotiateCharset(offers []string) string {
+	return negotiate(h.Get("accept-charset"), offers, matchCharset)
+}
+
+// matchFunc reports whether pref matches offer and how specific the match
+// is; the most specific matching preference decides the weight of an offer.
+type matchFunc func(pref Preference, offer string) (specificity int, ok bool)
+
+func negotiate(header string, offers []string, match matchFunc) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	if strings.TrimSpace(header) == "" {
+		return offers[0]
+	}
+	prefs := ParsePreferences(header)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, specificity := 0.0, -1
+		for _, pref := range prefs {
+			s, ok := match(pref, strings.ToLower(offer))
+			if ok && s > specificity {
+				q, specificity = pref.Q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+func matchMediaRange(pref Preference, offer string) (int, bool) {
+	offerType, offerParams, _ := strings.Cut(offer, ";")
+	typ, sub, _ := strings.Cut(strings.TrimSpace(offerType), "/")
+	prefType, prefSub, _ := strings.Cut(pref.Value, "/")
+	switch {
+	case prefType == "*" && prefSub == "*":
+		return 0, true
+	case prefType == typ && prefSub == "*":
+		return 1, true
+	case prefType != typ || prefSub != sub:
+		return 0, false
+	}
+	if len(pref.Params) == 0 {
+		return 2, true
+	}
+	params := map[string]string{}
+	for _, p := range strings.Split(offerParams, ";") {
+		k, v, _ := strings.Cut(p, "=")
+		params[strings.TrimSpace(k)] = strings.Trim(strings.TrimSpace(v), `"`)
+	}
+	for k, v := range pref.Params {
+		if params[k] != v {
+			return 0, false
+		}
+	}
+	return 3, true
+}
+
+func matchLanguageRange(pref Preference, offer string) (int, bool) {
+	if pref.Value == "*" {
+		return 0, true
+	}
+	if offer == pref.Value || strings.HasPrefix(offer, pref.Value+"-") {
+		return len(pref.Value), true
+	}
+	return 0, false
+}
+
+func matchCharset(pref Preference, offer string) (int, bool) {
+	if pref.Value == "*" {
+		return 0, true
+	}
+	return 1, offer == pref.Value
+}
@@ -0,0 +1,54 @@
+package headers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePreferences(t *testing.T) {
+	prefs := ParsePreferences("text/html;level=1, application/json;q=0.5, */*;q=0.1, text/plain;q=bogus")
+	assert.Equal(t, []Preference{
+		{Value: "text/html", Q: 1, Params: map[string]string{"level": "1"}},
+		{Value: "application/json", Q: 0.5},
+		{Value: "*/*", Q: 0.1},
+	}, prefs)
+}
+
+func TestNegotiateContentType(t *testing.T) {
+	h := NewHeaders()
+	offers := []string{"text/html", "application/json"}
+	assert.Equal(t, "text/html", h.NegotiateContentType(offers))
+
+	h["accept"] = "application/json"
+	assert.Equal(t, "application/json", h.NegotiateContentType(offers))
+
+	h["accept"] = "text/*;q=0.5, application/json;q=0.8"
+	assert.Equal(t, "application/json", h.NegotiateContentType(offers))
+
+	// The more specific range decides the weight of an offer.
+	h["accept"] = "*/*;q=0.9, text/html;q=0.1"
+	assert.Equal(t, "application/json", h.NegotiateContentType(offers))
+
+	h["accept"] = "image/png"
+	assert.Equal(t, "", h.NegotiateContentType(offers))
+
+	h["accept"] = "application/json;q=0"
+	assert.Equal(t, "", h.NegotiateContentType([]string{"application/json"}))
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	h := NewHeaders()
+	h["accept-language"] = "hu, en;q=0.8, *;q=0.1"
+	assert.Equal(t, "en-US", h.NegotiateLanguage([]string{"de", "en-US"}))
+	assert.Equal(t, "hu-HU", h.NegotiateLanguage([]string{"en", "hu-HU"}))
+	assert.Equal(t, "de", h.NegotiateLanguage([]string{"de"}))
+}
+
+func TestNegotiateCharset(t *testing.T) {
+	h := NewHeaders()
+	h["accept-charset"] = "iso-8859-1, utf-8;q=0.5"
+	assert.Equal(t, "ISO-8859-1", h.NegotiateCharset([]string{"utf-8", "ISO-8859-1"}))
+	h["accept-charset"] = "utf-16"
+	assert.Equal(t, "", h.NegotiateCharset([]string{"utf-8"}))
+}
@@ -0,0 +1,125 @@
+package jsonhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+)
+
+// ContentType is the media type of the JSON bodies written by Write.
+const ContentType = "application/json"
+
+// DefaultMaxBodySize is the largest body a Decoder accepts unless told
+// otherwise.
+const DefaultMaxBodySize = 1 << 20
+
+// Decoder decodes JSON request bodies.
+type Decoder struct {
+	// MaxBodySize is the largest body accepted in bytes; 0 means
+	// DefaultMaxBodySize.
+	MaxBodySize int64
+	// AllowUnknownFields accepts object members that match no field of
+	// the destination instead of rejecting the body.
+	AllowUnknownFields bool
+}
+
+// Decode decodes the body of req into v with the default Decoder.
+func Decode(req *request.Request, v any) error {
+	var d Decoder
+	return d.Decode(req, v)
+}
+
+// Decode decodes the body of req, which must be a single JSON value sent
+// as application/json or another +json type, into v. A body that cannot be
+// decoded is reported as a *Problem with the status to answer with: 415
+// for another content type, 413 for a body over MaxBodySize and 400 for
+// an invalid one.
+func (d *Decoder) Decode(req *request.Request, v any) error {
+	if !isJSON(req.Headers.Get("content-type")) {
+		return NewProblem(response.StatusUnsupportedMediaType, "Content-Type must be "+ContentType)
+	}
+	limit := d.MaxBodySize
+	if limit == 0 {
+		limit = DefaultMaxBodySize
+	}
+	// The server reads the whole body before the handler runs, so the
+	// limit bounds what is decoded rather than what is read.
+	if int64(len(req.Body)) > limit {
+		return NewProblem(response.StatusContentTooLarge, fmt.Sprintf("Body must not be larger than %d bytes", limit))
+	}
+	dec := json.NewDecoder(bytes.NewReader(req.Body))
+	if !d.AllowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return NewProblem(response.StatusBadRequest, decodeErrorDetail(err))
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return NewProblem(response.StatusBadRequest, "Body must hold a single JSON value")
+	}
+	return nil
+}
+
+// isJSON reports whether contentType is application/json or a structured
+// syntax suffix type such as application/merge-patch+json.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == ContentType || strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json")
+}
+
+// decodeErrorDetail describes a json.Decoder error to the client without
+// mentioning Go types.
+func decodeErrorDetail(err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return "Body must not be empty"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return "Body holds truncated JSON"
+	case errors.As(err, &syntaxErr):
+		return fmt.Sprintf("Body holds malformed JSON at offset %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return fmt.Sprintf("Body must not be a JSON %s", typeErr.Value)
+		}
+		return fmt.Sprintf("Field %q must not be a JSON %s", typeErr.Field, typeErr.Value)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return "Unknown field " + strings.TrimPrefix(err.Error(), "json: unknown field ")
+	}
+	return "Body holds invalid JSON"
+}
+
+// Write answers with code and v encoded as JSON. If v cannot be encoded,
+// a 500 problem is sent instead and the error returned.
+func Write(w *response.Writer, code response.StatusCode, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		WriteProblem(w, NewProblem(response.StatusInternalServerError, ""))
+		return err
+	}
+	return write(w, code, ContentType, data)
+}
+
+func write(w *response.Writer, code response.StatusCode, contentType string, data []byte) error {
+	if err := w.WriteStatusLine(code); err != nil {
+		return err
+	}
+	headers := response.GetDefaultHeaders(len(data))
+	headers = response.ReplaceHeader(map[string]string{"Content-Type": contentType}, headers)
+	if err := w.WriteHeaders(headers); err != nil {
+		return err
+	}
+	_, err := w.WriteBody(data)
+	return err
+}
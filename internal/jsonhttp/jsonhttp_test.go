@@ -0,0 +1,133 @@
+package jsonhttp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func jsonRequest(contentType, body string) *request.Request {
+	h := headers.NewHeaders()
+	if contentType != "" {
+		h.Set("content-type", contentType)
+	}
+	return &request.Request{
+		RequestLine: request.RequestLine{Method: "POST", RequestTarget: "/items", HttpVersion: "1.1"},
+		Headers:     h,
+		Body:        []byte(body),
+	}
+}
+
+// record runs write and parses the response it wrote.
+func record(t *testing.T, write func(w *response.Writer)) (*http.Response, string) {
+	var buf bytes.Buffer
+	write(&response.Writer{Writer: &buf})
+	resp, err := http.ReadResponse(bufio.NewReader(&buf), nil)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(body)
+}
+
+func TestDecode(t *testing.T) {
+	var v item
+	require.NoError(t, Decode(jsonRequest("application/json; charset=utf-8", `{"name":"pen","count":3}`), &v))
+	assert.Equal(t, item{Name: "pen", Count: 3}, v)
+	require.NoError(t, Decode(jsonRequest("application/merge-patch+json", `{"count":4}`), &v))
+	assert.Equal(t, 4, v.Count)
+
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		body        string
+		status      response.StatusCode
+		detail      string
+	}{
+		{"no content type", "", `{}`, response.StatusUnsupportedMediaType, "Content-Type must be application/json"},
+		{"other content type", "text/plain", `{}`, response.StatusUnsupportedMediaType, "Content-Type must be application/json"},
+		{"empty", "application/json", ``, response.StatusBadRequest, "Body must not be empty"},
+		{"malformed", "application/json", `{"name":}`, response.StatusBadRequest, "Body holds malformed JSON at offset 9"},
+		{"truncated", "application/json", `{"name":"pen"`, response.StatusBadRequest, "Body holds truncated JSON"},
+		{"wrong type", "application/json", `{"count":"3"}`, response.StatusBadRequest, `Field "count" must not be a JSON string`},
+		{"not an object", "application/json", `[1]`, response.StatusBadRequest, "Body must not be a JSON array"},
+		{"unknown field", "application/json", `{"price":1}`, response.StatusBadRequest, `Unknown field "price"`},
+		{"two values", "application/json", `{} {}`, response.StatusBadRequest, "Body must hold a single JSON value"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var v item
+			err := Decode(jsonRequest(tc.contentType, tc.body), &v)
+			var p *Problem
+			require.True(t, errors.As(err, &p))
+			assert.Equal(t, tc.status, p.Status)
+			assert.Equal(t, tc.detail, p.Detail)
+		})
+	}
+
+	// Test: Size limit and unknown fields allowed
+	d := Decoder{MaxBodySize: 16, AllowUnknownFields: true}
+	require.NoError(t, d.Decode(jsonRequest("application/json", `{"price":1}`), &v))
+	err := d.Decode(jsonRequest("application/json", `{"name":"a long name"}`), &v)
+	var p *Problem
+	require.True(t, errors.As(err, &p))
+	assert.Equal(t, response.StatusContentTooLarge, p.Status)
+}
+
+func TestWrite(t *testing.T) {
+	resp, body := record(t, func(w *response.Writer) {
+		require.NoError(t, Write(w, response.StatusOK, item{Name: "pen", Count: 3}))
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, ContentType, resp.Header.Get("Content-Type"))
+	assert.Equal(t, int64(len(body)), resp.ContentLength)
+	assert.Equal(t, `{"name":"pen","count":3}`, body)
+
+	// Test: Values that cannot be encoded
+	resp, body = record(t, func(w *response.Writer) {
+		assert.Error(t, Write(w, response.StatusOK, math.Inf(1)))
+	})
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, ProblemContentType, resp.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"title":"Internal Server Error","status":500}`, body)
+}
+
+func TestWriteProblem(t *testing.T) {
+	p := NewProblem(response.StatusForbidden, "Your balance is 30, the item costs 50")
+	p.Type = "https://example.com/probs/out-of-credit"
+	p.Instance = "/account/12345/msgs/abc"
+	p.Extensions = map[string]any{"balance": 30, "status": 200}
+	resp, body := record(t, func(w *response.Writer) {
+		require.NoError(t, WriteError(w, p))
+	})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, ProblemContentType, resp.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"type": "https://example.com/probs/out-of-credit",
+		"title": "Forbidden",
+		"status": 403,
+		"detail": "Your balance is 30, the item costs 50",
+		"instance": "/account/12345/msgs/abc",
+		"balance": 30
+	}`, body)
+
+	// Test: Other errors are not shown to the client
+	resp, body = record(t, func(w *response.Writer) {
+		require.NoError(t, WriteError(w, errors.New("database password is hunter2")))
+	})
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.NotContains(t, body, "hunter2")
+}
@@ -0,0 +1,88 @@
+package jsonhttp
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/danielNemeth19/http-protocol/internal/response"
+)
+
+// ProblemContentType is the media type of problem details, RFC 9457.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 9457 problem details object. It is an error, so
+// helpers can return it for the handler to write with WriteError.
+type Problem struct {
+	// Type is a URI identifying the problem type; empty means
+	// "about:blank", a problem described by its status alone.
+	Type string `json:"type,omitempty"`
+	// Title is a short summary of the problem type.
+	Title  string              `json:"title,omitempty"`
+	Status response.StatusCode `json:"status,omitempty"`
+	// Detail explains this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+	// Instance is a URI identifying this occurrence of the problem.
+	Instance string `json:"instance,omitempty"`
+	// Extensions are additional members. They cannot replace the ones
+	// above.
+	Extensions map[string]any `json:"-"`
+}
+
+// NewProblem returns a Problem of type "about:blank" for status, titled
+// with its reason phrase.
+func NewProblem(status response.StatusCode, detail string) *Problem {
+	return &Problem{Title: response.StatusText(status), Status: status, Detail: detail}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Title + ": " + p.Detail
+}
+
+// MarshalJSON encodes the extension members alongside the standard ones.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	data, err := json.Marshal((*problem)(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return data, err
+	}
+	members := make(map[string]any, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+	var standard map[string]any
+	if err := json.Unmarshal(data, &standard); err != nil {
+		return nil, err
+	}
+	for k, v := range standard {
+		members[k] = v
+	}
+	return json.Marshal(members)
+}
+
+// WriteProblem answers with p as application/problem+json. A Problem
+// without Status is sent as a 500.
+func WriteProblem(w *response.Writer, p *Problem) error {
+	code := p.Status
+	if code == 0 {
+		code = response.StatusInternalServerError
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return write(w, code, ProblemContentType, data)
+}
+
+// WriteError answers with the *Problem in err's chain, such as one
+// returned by Decode, or with a bare 500 problem for any other error,
+// whose text is not shown to the client.
+func WriteError(w *response.Writer, err error) error {
+	var p *Problem
+	if !errors.As(err, &p) {
+		p = NewProblem(response.StatusInternalServerError, "")
+	}
+	return WriteProblem(w, p)
+}
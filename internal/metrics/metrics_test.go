@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryWriteTo(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounter("requests_total", "Requests served.", "method", "status")
+	active := r.NewGauge("active", "Active things.")
+	duration := r.NewHistogram("duration_seconds", "Duration.", []float64{0.1, 1}, "method")
+
+	requests.Inc("GET", "200")
+	requests.Inc("GET", "200")
+	requests.Add(3, "POST", `5"0\0`)
+	active.Inc()
+	active.Inc()
+	active.Dec()
+	duration.Observe(0.05, "GET")
+	duration.Observe(0.1, "GET")
+	duration.Observe(3, "GET")
+
+	var buf bytes.Buffer
+	_, err := r.WriteTo(&buf)
+	require.NoError(t, err)
+	expected := `# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{method="GET",status="200"} 2
+requests_total{method="POST",status="5\"0\\0"} 3
+# HELP active Active things.
+# TYPE active gauge
+active 1
+# HELP duration_seconds Duration.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{method="GET",le="0.1"} 2
+duration_seconds_bucket{method="GET",le="1"} 2
+duration_seconds_bucket{method="GET",le="+Inf"} 3
+duration_seconds_sum{method="GET"} 3.15
+duration_seconds_count{method="GET"} 3
+`
+	assert.Equal(t, expected, buf.String())
+	assert.Equal(t, float64(2), requests.Value("GET", "200"))
+	assert.Equal(t, uint64(3), duration.Count("GET"))
+}
+
+func TestRegistryPanics(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("c", "help", "label")
+	assert.Panics(t, func() { r.NewGauge("c", "help") })
+	assert.Panics(t, func() { c.Inc() })
+	assert.Panics(t, func() { c.Add(-1, "x") })
+	assert.Panics(t, func() { r.NewHistogram("h", "help", []float64{2, 1}) })
+}
+
+func TestRegistryHandle(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("hits_total", "Hits.").Inc()
+	var buf bytes.Buffer
+	req := &request.Request{
+		RequestLine: request.RequestLine{Method: "GET", RequestTarget: "/metrics", HttpVersion: "1.1"},
+		Headers:     headers.NewHeaders(),
+	}
+	r.Handle(&response.Writer{Writer: &buf}, req)
+	resp, err := response.ResponseFromReader(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, response.StatusOK, resp.StatusLine.StatusCode)
+	assert.Equal(t, ContentType, resp.Headers.Get("content-type"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "hits_total 1\n")
+}
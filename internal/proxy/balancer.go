@@ -0,0 +1,232 @@
+package proxy
+
+import (
+	"cmp"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/client"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+)
+
+type Strategy int
+
+const (
+	RoundRobin Strategy = iota
+	LeastConnections
+	ConsistentHash
+)
+
+// ParseStrategy maps "round-robin", "least-conn" and "hash" to a Strategy.
+func ParseStrategy(s string) (Strategy, error) {
+	switch s {
+	case "round-robin", "":
+		return RoundRobin, nil
+	case "least-conn":
+		return LeastConnections, nil
+	case "hash":
+		return ConsistentHash, nil
+	}
+	return 0, fmt.Errorf("Unknown load balancing strategy: %s", s)
+}
+
+const (
+	defaultMaxFails = 3
+	defaultEjectFor = 30 * time.Second
+	// virtualNodes is the number of points every backend gets on the
+	// consistent hash ring.
+	virtualNodes = 100
+)
+
+// Backend is one upstream server of a Pool.
+type Backend struct {
+	URL          *url.URL
+	healthy      atomic.Bool
+	active       atomic.Int64
+	failures     atomic.Int32
+	ejectedUntil atomic.Int64
+}
+
+// ActiveRequests returns the number of requests in flight to the backend.
+func (b *Backend) ActiveRequests() int64 {
+	return b.active.Load()
+}
+
+func (b *Backend) available(now time.Time) bool {
+	return b.healthy.Load() && now.UnixNano() >= b.ejectedUntil.Load()
+}
+
+type ringPoint struct {
+	hash    uint32
+	backend *Backend
+}
+
+// Pool picks a backend for every request and tracks backend health: a
+// backend is skipped while its active health check fails and, passively,
+// for EjectFor after MaxFails consecutive failed requests.
+type Pool struct {
+	Strategy Strategy
+	// HashKey derives the consistent hashing key of a request; nil means
+	// the client IP.
+	HashKey func(req *request.Request) string
+	// MaxFails and EjectFor control passive ejection; zero values mean 3
+	// failures and 30 seconds.
+	MaxFails int
+	EjectFor time.Duration
+
+	backends []*Backend
+	ring     []ringPoint
+	next     atomic.Uint64
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewPool returns a Pool over the given upstream base URLs.
+func NewPool(targets []string, strategy Strategy) (*Pool, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("Pool needs at least one upstream")
+	}
+	p := &Pool{Strategy: strategy, stop: make(chan struct{})}
+	for _, target := range targets {
+		rp, err := NewReverseProxy(target)
+		if err != nil {
+			return nil, err
+		}
+		b := &Backend{URL: rp.Target}
+		b.healthy.Store(true)
+		p.backends = append(p.backends, b)
+		for i := range virtualNodes {
+			h := crc32.ChecksumIEEE([]byte(target + "#" + strconv.Itoa(i)))
+			p.ring = append(p.ring, ringPoint{hash: h, backend: b})
+		}
+	}
+	slices.SortFunc(p.ring, func(a, b ringPoint) int {
+		return cmp.Compare(a.hash, b.hash)
+	})
+	return p, nil
+}
+
+// Backends returns the backends of the pool.
+func (p *Pool) Backends() []*Backend {
+	return p.backends
+}
+
+// pick selects an available backend not in exclude, or nil if there is
+// none.
+func (p *Pool) pick(req *request.Request, exclude []*Backend) *Backend {
+	now := time.Now()
+	usable := func(b *Backend) bool {
+		return b.available(now) && !slices.Contains(exclude, b)
+	}
+	switch p.Strategy {
+	case LeastConnections:
+		var best *Backend
+		for _, b := range p.backends {
+			if usable(b) && (best == nil || b.active.Load() < best.active.Load()) {
+				best = b
+			}
+		}
+		return best
+	case ConsistentHash:
+		key := p.hashKey(req)
+		h := crc32.ChecksumIEEE([]byte(key))
+		start, _ := slices.BinarySearchFunc(p.ring, h, func(rp ringPoint, h uint32) int {
+			return cmp.Compare(rp.hash, h)
+		})
+		for i := range p.ring {
+			b := p.ring[(start+i)%len(p.ring)].backend
+			if usable(b) {
+				return b
+			}
+		}
+		return nil
+	default:
+		n := uint64(len(p.backends))
+		start := p.next.Add(1) - 1
+		for i := range n {
+			b := p.backends[(start+i)%n]
+			if usable(b) {
+				return b
+			}
+		}
+		return nil
+	}
+}
+
+func (p *Pool) hashKey(req *request.Request) string {
+	if p.HashKey != nil {
+		return p.HashKey(req)
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+func (p *Pool) markSuccess(b *Backend) {
+	b.failures.Store(0)
+}
+
+func (p *Pool) markFailure(b *Backend) {
+	maxFails := p.MaxFails
+	if maxFails <= 0 {
+		maxFails = defaultMaxFails
+	}
+	if int(b.failures.Add(1)) < maxFails {
+		return
+	}
+	ejectFor := p.EjectFor
+	if ejectFor <= 0 {
+		ejectFor = defaultEjectFor
+	}
+	b.failures.Store(0)
+	b.ejectedUntil.Store(time.Now().Add(ejectFor).UnixNano())
+}
+
+// StartHealthChecks probes path on every backend each interval with a GET;
+// a backend is healthy while it answers with a status below 400. Checks
+// run until Stop is called.
+func (p *Pool) StartHealthChecks(path string, interval, timeout time.Duration) {
+	c := &client.Client{Timeout: timeout, DialTimeout: timeout}
+	check := func() {
+		for _, b := range p.backends {
+			u := *b.URL
+			u.Path = singleJoiningSlash(b.URL.Path, path)
+			resp, err := c.Get(u.String())
+			if err != nil {
+				b.healthy.Store(false)
+				continue
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			b.healthy.Store(resp.StatusLine.StatusCode < 400)
+		}
+	}
+	check()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				check()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the active health checks.
+func (p *Pool) Stop() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func namedUpstream(t *testing.T, name string) *httptest.Server {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Write([]byte(name))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func bodyOf(t *testing.T, p *ReverseProxy, req *request.Request) string {
+	resp := serve(t, p.Handle, req)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestParseStrategy(t *testing.T) {
+	s, err := ParseStrategy("least-conn")
+	require.NoError(t, err)
+	assert.Equal(t, LeastConnections, s)
+	_, err = ParseStrategy("random")
+	require.Error(t, err)
+}
+
+func TestPoolRoundRobin(t *testing.T) {
+	a, b := namedUpstream(t, "a"), namedUpstream(t, "b")
+	pool, err := NewPool([]string{a.URL, b.URL}, RoundRobin)
+	require.NoError(t, err)
+	p := NewBalancedProxy(pool)
+
+	var got []string
+	for range 4 {
+		got = append(got, bodyOf(t, p, newRequest("GET", "/", "", nil)))
+	}
+	assert.Equal(t, []string{"a", "b", "a", "b"}, got)
+}
+
+func TestPoolLeastConnections(t *testing.T) {
+	pool, err := NewPool([]string{"http://a.invalid", "http://b.invalid"}, LeastConnections)
+	require.NoError(t, err)
+	pool.backends[0].active.Store(5)
+	pool.backends[1].active.Store(1)
+	assert.Equal(t, pool.backends[1], pool.pick(newRequest("GET", "/", "", nil), nil))
+}
+
+func TestPoolConsistentHash(t *testing.T) {
+	pool, err := NewPool([]string{"http://a.invalid", "http://b.invalid", "http://c.invalid"}, ConsistentHash)
+	require.NoError(t, err)
+	req := newRequest("GET", "/", "", nil)
+	first := pool.pick(req, nil)
+	for range 10 {
+		assert.Equal(t, first, pool.pick(req, nil))
+	}
+
+	// Ejecting the chosen backend moves the key to another one.
+	first.ejectedUntil.Store(time.Now().Add(time.Minute).UnixNano())
+	second := pool.pick(req, nil)
+	require.NotNil(t, second)
+	assert.NotEqual(t, first, second)
+}
+
+func TestPoolRetriesIdempotentRequestOnOtherBackend(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	alive := namedUpstream(t, "alive")
+	pool, err := NewPool([]string{dead.URL, alive.URL}, RoundRobin)
+	require.NoError(t, err)
+	p := NewBalancedProxy(pool)
+
+	assert.Equal(t, "alive", bodyOf(t, p, newRequest("GET", "/", "", nil)))
+
+	// POST is not retried: round robin now points at the dead backend.
+	pool.next.Store(0)
+	resp := serve(t, p.Handle, newRequest("POST", "/", "x", nil))
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+}
+
+func TestPoolPassiveEjection(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	pool, err := NewPool([]string{dead.URL}, RoundRobin)
+	require.NoError(t, err)
+	pool.MaxFails = 2
+	p := NewBalancedProxy(pool)
+
+	for range 2 {
+		resp := serve(t, p.Handle, newRequest("GET", "/", "", nil))
+		assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	}
+	resp := serve(t, p.Handle, newRequest("GET", "/", "", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+func TestPoolRequestDeadlineDoesNotEject(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("slow"))
+	}))
+	defer slow.Close()
+	pool, err := NewPool([]string{slow.URL}, RoundRobin)
+	require.NoError(t, err)
+	pool.MaxFails = 1
+	p := NewBalancedProxy(pool)
+
+	// The request context ends first, e.g. on the server's write timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req := newRequest("GET", "/", "", nil).WithContext(ctx)
+	p.Handle(&response.Writer{Writer: io.Discard}, req)
+
+	assert.Equal(t, "slow", bodyOf(t, p, newRequest("GET", "/", "", nil)))
+
+	// Test: The proxy's own timeout still counts against the backend
+	p.Timeout = 20 * time.Millisecond
+	resp := serve(t, p.Handle, newRequest("GET", "/", "", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	resp = serve(t, p.Handle, newRequest("GET", "/", "", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+func TestPoolActiveHealthChecks(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" && !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer upstream.Close()
+	pool, err := NewPool([]string{upstream.URL}, RoundRobin)
+	require.NoError(t, err)
+	pool.StartHealthChecks("/health", 10*time.Millisecond, time.Second)
+	defer pool.Stop()
+
+	assert.True(t, pool.backends[0].healthy.Load())
+	healthy.Store(false)
+	assert.Eventually(t, func() bool { return !pool.backends[0].healthy.Load() }, time.Second, 5*time.Millisecond)
+	healthy.Store(true)
+	assert.Eventually(t, func() bool { return pool.backends[0].healthy.Load() }, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,256 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/client"
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+)
+
+const defaultDialTimeout = 10 * time.Second
+
+// ErrPrivateDestination is returned by DenyPrivate for addresses that are
+// not public.
+var ErrPrivateDestination = errors.New("Destination address is not public")
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, which
+// netip does not count as private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// DenyPrivate is a net.Dialer Control function refusing connections to
+// loopback, link-local, private and other addresses that are not public
+// unicast, such as 169.254.169.254. It sees the address being dialed, so
+// host names resolving to such addresses are refused as well.
+func DenyPrivate(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	addr := ap.Addr().Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() || sharedAddressSpace.Contains(addr) {
+		return fmt.Errorf("%w: %s", ErrPrivateDestination, addr)
+	}
+	return nil
+}
+
+// ForwardProxy is an HTTP forward proxy: absolute-form requests
+// ("GET http://host/path") are forwarded to the named origin, and CONNECT
+// requests open a TCP tunnel to host:port.
+type ForwardProxy struct {
+	// AllowedPorts lists the destination ports requests may reach. Empty
+	// means only 443 for CONNECT, and 80 and 443 for absolute-form
+	// requests.
+	AllowedPorts []int
+	// AllowPrivate lets requests reach the addresses DenyPrivate refuses,
+	// which are otherwise off limits so the proxy cannot be used against
+	// internal services.
+	AllowPrivate bool
+	// Authenticate checks the Basic credentials from Proxy-Authorization;
+	// nil disables proxy authentication.
+	Authenticate func(user, password string) bool
+	// Realm is announced in the Proxy-Authenticate challenge.
+	Realm string
+	// Client performs plain HTTP requests; nil means a client with
+	// default settings that honours AllowPrivate. A Client given here is
+	// used as is: set its Control to DenyPrivate to keep private
+	// addresses refused.
+	Client      *client.Client
+	DialTimeout time.Duration
+
+	clientOnce    sync.Once
+	defaultClient *client.Client
+}
+
+// IsProxyRequest reports whether req is addressed to a forward proxy
+// rather than to this server.
+func IsProxyRequest(req *request.Request) bool {
+	target := req.RequestLine.RequestTarget
+	return req.RequestLine.Method == "CONNECT" ||
+		strings.HasPrefix(target, "http://") ||
+		strings.HasPrefix(target, "https://")
+}
+
+// Handle is a server.Handler serving proxy requests.
+func (p *ForwardProxy) Handle(w *response.Writer, req *request.Request) {
+	if !p.authorized(req) {
+		realm := p.Realm
+		if realm == "" {
+			realm = "proxy"
+		}
+		message := "Proxy authentication required\n"
+		h := response.GetDefaultHeaders(len(message))
+		h.Set("Proxy-Authenticate", fmt.Sprintf("Basic realm=%q", realm))
+		w.WriteStatusLine(response.StatusProxyAuthRequired)
+		w.WriteHeaders(h)
+		w.WriteBody([]byte(message))
+		return
+	}
+	if req.RequestLine.Method == "CONNECT" {
+		p.tunnel(w, req)
+		return
+	}
+
+	u, err := url.Parse(req.RequestLine.RequestTarget)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		writeError(w, response.StatusBadRequest, errors.New("Proxy requests need an absolute http URL"))
+		return
+	}
+	portStr := u.Port()
+	if portStr == "" {
+		portStr = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		writeError(w, response.StatusBadRequest, fmt.Errorf("Invalid port: %s", portStr))
+		return
+	}
+	if !p.portAllowed(port, 80, 443) {
+		writeError(w, response.StatusForbidden, fmt.Errorf("Requests to port %d are not allowed", port))
+		return
+	}
+	origin := &ReverseProxy{
+		Target: &url.URL{Scheme: u.Scheme, Host: u.Host},
+		Client: p.httpClient(),
+	}
+	// The origin gets a rewritten copy; middleware around the proxy,
+	// such as the access log, keeps seeing the request as received.
+	out := *req
+	out.RequestLine.RequestTarget = u.RequestURI()
+	out.Headers = maps.Clone(req.Headers)
+	out.Headers["host"] = u.Host
+	origin.Handle(w, &out)
+}
+
+func (p *ForwardProxy) httpClient() *client.Client {
+	if p.Client != nil {
+		return p.Client
+	}
+	p.clientOnce.Do(func() {
+		p.defaultClient = &client.Client{Control: p.control()}
+	})
+	return p.defaultClient
+}
+
+// control returns the Control function of the proxy's dialers.
+func (p *ForwardProxy) control() func(network, address string, c syscall.RawConn) error {
+	if p.AllowPrivate {
+		return nil
+	}
+	return DenyPrivate
+}
+
+// portAllowed reports whether port is in AllowedPorts or, if that is
+// empty, in defaults.
+func (p *ForwardProxy) portAllowed(port int, defaults ...int) bool {
+	allowed := p.AllowedPorts
+	if len(allowed) == 0 {
+		allowed = defaults
+	}
+	return slices.Contains(allowed, port)
+}
+
+func (p *ForwardProxy) authorized(req *request.Request) bool {
+	if p.Authenticate == nil {
+		return true
+	}
+	scheme, credentials, _ := strings.Cut(req.Headers.Get("proxy-authorization"), " ")
+	if !strings.EqualFold(scheme, "basic") {
+		return false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(credentials))
+	if err != nil {
+		return false
+	}
+	user, password, found := strings.Cut(string(decoded), ":")
+	return found && p.Authenticate(user, password)
+}
+
+// tunnel connects to the authority-form target of a CONNECT request and
+// splices bytes between it and the client until either side is done.
+func (p *ForwardProxy) tunnel(w *response.Writer, req *request.Request) {
+	address := req.RequestLine.RequestTarget
+	_, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		writeError(w, response.StatusBadRequest, err)
+		return
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		writeError(w, response.StatusBadRequest, fmt.Errorf("Invalid port: %s", portStr))
+		return
+	}
+	if !p.portAllowed(port, 443) {
+		writeError(w, response.StatusForbidden, fmt.Errorf("CONNECT to port %d is not allowed", port))
+		return
+	}
+
+	timeout := p.DialTimeout
+	if timeout == 0 {
+		timeout = defaultDialTimeout
+	}
+	dialer := &net.Dialer{Timeout: timeout, Control: p.control()}
+	upstream, err := dialer.Dial("tcp", address)
+	if errors.Is(err, ErrPrivateDestination) {
+		writeError(w, response.StatusForbidden, errors.New("Destination not allowed"))
+		return
+	}
+	if err != nil {
+		log.Printf("CONNECT to %s failed: %v\n", address, err)
+		writeStatus(w, response.StatusBadGateway)
+		return
+	}
+	// A 2xx answer to CONNECT carries no body framing headers,
+	// RFC 9110 section 9.3.6.
+	w.WriteStatusLine(response.StatusOK)
+	w.WriteHeaders(headers.NewHeaders())
+	client, buffered, err := w.Hijack()
+	if err != nil {
+		upstream.Close()
+		log.Printf("Cannot hijack connection for tunnel: %v\n", err)
+		return
+	}
+	if len(buffered) > 0 {
+		if _, err := upstream.Write(buffered); err != nil {
+			upstream.Close()
+			client.Close()
+			return
+		}
+	}
+	splice(client, upstream)
+}
+
+func splice(a, b net.Conn) {
+	var wg sync.WaitGroup
+	pipe := func(dst, src net.Conn) {
+		defer wg.Done()
+		io.Copy(dst, src)
+		// Half-close so the other direction can still drain.
+		if tcp, ok := dst.(*net.TCPConn); ok {
+			tcp.CloseWrite()
+		} else {
+			dst.Close()
+		}
+	}
+	wg.Add(2)
+	go pipe(a, b)
+	go pipe(b, a)
+	wg.Wait()
+	a.Close()
+	b.Close()
+}
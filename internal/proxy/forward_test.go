@@ -0,0 +1,188 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startProxy serves p on a loopback listener, one request per connection.
+func startProxy(t *testing.T, p *ForwardProxy) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				req, err := request.RequestFromReader(conn)
+				if err != nil {
+					conn.Close()
+					return
+				}
+				p.Handle(&response.Writer{Writer: conn}, req)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func startEchoListener(t *testing.T) (string, int) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	return listener.Addr().String(), listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestForwardProxyConnectTunnel(t *testing.T) {
+	echoAddr, echoPort := startEchoListener(t)
+	proxyAddr := startProxy(t, &ForwardProxy{AllowedPorts: []int{echoPort}, AllowPrivate: true})
+
+	conn, err := net.Dial("tcp", proxyAddr)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("CONNECT " + echoAddr + " HTTP/1.1\r\nHost: " + echoAddr + "\r\n\r\n"))
+	require.NoError(t, err)
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, &http.Request{Method: "CONNECT"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, err = conn.Write([]byte("ping through tunnel"))
+	require.NoError(t, err)
+	buf := make([]byte, len("ping through tunnel"))
+	_, err = io.ReadFull(br, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping through tunnel", string(buf))
+}
+
+func TestForwardProxyConnectPortNotAllowed(t *testing.T) {
+	echoAddr, _ := startEchoListener(t)
+	proxyAddr := startProxy(t, &ForwardProxy{})
+
+	conn, err := net.Dial("tcp", proxyAddr)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("CONNECT " + echoAddr + " HTTP/1.1\r\n\r\n"))
+	require.NoError(t, err)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: "CONNECT"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestForwardProxyAbsoluteForm(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/page", r.URL.Path)
+		assert.Empty(t, r.Header.Get("Proxy-Authorization"))
+		w.Write([]byte("origin says hi to " + r.Host))
+	}))
+	defer origin.Close()
+
+	p := &ForwardProxy{
+		AllowedPorts: []int{origin.Listener.Addr().(*net.TCPAddr).Port},
+		AllowPrivate: true,
+		Authenticate: func(user, password string) bool {
+			return user == "alice" && password == "secret"
+		},
+	}
+	host := origin.Listener.Addr().String()
+	credentials := base64.StdEncoding.EncodeToString([]byte("alice:secret"))
+	req := newRequest("GET", "http://"+host+"/page", "", map[string]string{
+		"Host":                host,
+		"Proxy-Authorization": "Basic " + credentials,
+	})
+	resp := serve(t, p.Handle, req)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "origin says hi to "+host, string(body))
+	// Test: The request seen by outer handlers is left as received
+	assert.Equal(t, "http://"+host+"/page", req.RequestLine.RequestTarget)
+	assert.NotEmpty(t, req.Headers.Get("proxy-authorization"))
+}
+
+func TestForwardProxyAbsoluteFormPortNotAllowed(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Request reached a port that is not allowed")
+	}))
+	defer origin.Close()
+
+	p := &ForwardProxy{}
+	host := origin.Listener.Addr().String()
+	resp := serve(t, p.Handle, newRequest("GET", "http://"+host+"/", "", nil))
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = serve(t, p.Handle, newRequest("GET", "http://127.0.0.1:6379/", "", nil))
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = serve(t, p.Handle, newRequest("GET", "http://127.0.0.1:http/", "", nil))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestForwardProxyPrivateDestinationNotAllowed(t *testing.T) {
+	echoAddr, echoPort := startEchoListener(t)
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Request reached a private address")
+	}))
+	defer origin.Close()
+	originPort := origin.Listener.Addr().(*net.TCPAddr).Port
+	p := &ForwardProxy{AllowedPorts: []int{echoPort, originPort}}
+
+	// Test: CONNECT
+	conn, err := net.Dial("tcp", startProxy(t, p))
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("CONNECT " + echoAddr + " HTTP/1.1\r\n\r\n"))
+	require.NoError(t, err)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: "CONNECT"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Test: Absolute-form, also by a name resolving to loopback
+	for _, host := range []string{origin.Listener.Addr().String(), "localhost:" + strconv.Itoa(originPort)} {
+		resp = serve(t, p.Handle, newRequest("GET", "http://"+host+"/", "", nil))
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, host)
+	}
+
+	for _, address := range []string{"127.0.0.1:80", "[::1]:80", "10.1.2.3:80", "192.168.0.1:443", "169.254.169.254:80", "100.64.0.1:80", "0.0.0.0:80", "[::ffff:127.0.0.1]:80", "[fe80::1]:80"} {
+		assert.ErrorIs(t, DenyPrivate("tcp", address, nil), ErrPrivateDestination, address)
+	}
+	assert.NoError(t, DenyPrivate("tcp", "93.184.215.14:443", nil))
+	assert.NoError(t, DenyPrivate("tcp", "[2606:4700::1]:443", nil))
+}
+
+func TestForwardProxyRequiresAuthentication(t *testing.T) {
+	p := &ForwardProxy{Realm: "corp", Authenticate: func(user, password string) bool { return false }}
+	resp := serve(t, p.Handle, newRequest("GET", "http://example.com/", "", nil))
+	assert.Equal(t, http.StatusProxyAuthRequired, resp.StatusCode)
+	assert.Equal(t, `Basic realm="corp"`, resp.Header.Get("Proxy-Authenticate"))
+}
+
+func TestIsProxyRequest(t *testing.T) {
+	assert.True(t, IsProxyRequest(newRequest("CONNECT", "example.com:443", "", nil)))
+	assert.True(t, IsProxyRequest(newRequest("GET", "http://example.com/", "", nil)))
+	assert.False(t, IsProxyRequest(newRequest("GET", "/", "", nil)))
+}
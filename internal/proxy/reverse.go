@@ -0,0 +1,351 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/client"
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/danielNemeth19/http-protocol/internal/server"
+)
+
+// hopHeaders are meaningful only for a single transport-level connection
+// and must not be forwarded, RFC 9110 section 7.6.1.
+var hopHeaders = []string{
+	"connection",
+	"proxy-connection",
+	"keep-alive",
+	"proxy-authenticate",
+	"proxy-authorization",
+	"te",
+	"trailer",
+	"transfer-encoding",
+	"upgrade",
+}
+
+// idempotentMethods may be retried on another backend, RFC 9110 section
+// 9.2.2.
+var idempotentMethods = []string{"GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE"}
+
+// ReverseProxy forwards requests to an upstream server and relays its
+// response back to the client.
+type ReverseProxy struct {
+	// Target is the base URL of the upstream; the request target is
+	// appended to its path. It is ignored when Pool is set.
+	Target *url.URL
+	// Pool, if set, balances requests over several upstreams.
+	Pool *Pool
+	// Retries is how many other backends of the Pool an idempotent
+	// request is retried on when the upstream cannot be reached.
+	Retries int
+	// Client performs the upstream round trip; nil means
+	// client.DefaultClient.
+	Client *client.Client
+	// PreserveHost forwards the client's Host header instead of the
+	// host of Target.
+	PreserveHost bool
+	// Timeout bounds the upstream round trip including the response
+	// body; 0 means no limit.
+	Timeout time.Duration
+}
+
+// NewReverseProxy returns a ReverseProxy forwarding to target, which must
+// be an absolute http or https URL.
+func NewReverseProxy(target string) (*ReverseProxy, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("Invalid upstream URL: %s", target)
+	}
+	return &ReverseProxy{Target: u}, nil
+}
+
+// NewBalancedProxy returns a ReverseProxy spreading requests over pool,
+// retrying idempotent requests on every other backend.
+func NewBalancedProxy(pool *Pool) *ReverseProxy {
+	return &ReverseProxy{Pool: pool, Retries: len(pool.backends) - 1}
+}
+
+// Handle is a server.Handler forwarding req to the upstream.
+func (p *ReverseProxy) Handle(w *response.Writer, req *request.Request) {
+	ctx := req.Context()
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+	target, err := url.Parse(req.RequestLine.RequestTarget)
+	if err != nil {
+		writeError(w, response.StatusBadRequest, err)
+		return
+	}
+	c := p.Client
+	if c == nil {
+		c = client.DefaultClient
+	}
+	attempts := 1
+	if p.Pool != nil && slices.Contains(idempotentMethods, req.RequestLine.Method) {
+		attempts += p.Retries
+	}
+
+	var tried []*Backend
+	var lastErr error
+	for range attempts {
+		base := p.Target
+		var backend *Backend
+		if p.Pool != nil {
+			backend = p.Pool.pick(req, tried)
+			if backend == nil {
+				break
+			}
+			tried = append(tried, backend)
+			base = backend.URL
+			backend.active.Add(1)
+		}
+		resp, err := p.roundTrip(ctx, c, base, target, req)
+		if err != nil && req.Context().Err() != nil {
+			// The client went away, the server's write timeout expired
+			// or it is shutting down; that says nothing about the
+			// backend. Only p.Timeout or the upstream failing count.
+			if backend != nil {
+				backend.active.Add(-1)
+			}
+			log.Printf("Upstream request cancelled: %v\n", err)
+			return
+		}
+		if err != nil {
+			if backend != nil {
+				backend.active.Add(-1)
+				p.Pool.markFailure(backend)
+			}
+			log.Printf("Upstream request failed: %v\n", err)
+			lastErr = err
+			if errors.Is(err, context.DeadlineExceeded) {
+				break
+			}
+			continue
+		}
+		if backend != nil {
+			if resp.StatusLine.StatusCode >= 500 {
+				p.Pool.markFailure(backend)
+			} else {
+				p.Pool.markSuccess(backend)
+			}
+		}
+		err = copyResponse(w, req, resp)
+		resp.Body.Close()
+		if backend != nil {
+			backend.active.Add(-1)
+		}
+		if err != nil {
+			log.Printf("Relaying upstream response failed: %v\n", err)
+		}
+		return
+	}
+
+	switch {
+	case lastErr == nil:
+		writeError(w, response.StatusServiceUnavailable, errors.New("No healthy upstream"))
+	case errors.Is(lastErr, ErrPrivateDestination):
+		writeError(w, response.StatusForbidden, errors.New("Destination not allowed"))
+	case errors.Is(lastErr, context.DeadlineExceeded):
+		writeStatus(w, response.StatusGatewayTimeout)
+	default:
+		writeStatus(w, response.StatusBadGateway)
+	}
+}
+
+func (p *ReverseProxy) roundTrip(ctx context.Context, c *client.Client, base, target *url.URL, req *request.Request) (*response.Response, error) {
+	u := *base
+	u.Path = singleJoiningSlash(base.Path, target.Path)
+	switch {
+	case base.RawQuery == "":
+		u.RawQuery = target.RawQuery
+	case target.RawQuery != "":
+		u.RawQuery = base.RawQuery + "&" + target.RawQuery
+	}
+	outreq := &client.Request{
+		Method:  req.RequestLine.Method,
+		URL:     &u,
+		Headers: headers.NewHeaders(),
+		Body:    req.Body,
+	}
+	copyRequestHeaders(outreq.Headers, req.Headers)
+	if p.PreserveHost {
+		outreq.Headers["host"] = req.Headers.Get("host")
+	}
+	addForwardedHeaders(outreq.Headers, req)
+	return c.Do(ctx, outreq)
+}
+
+// copyRequestHeaders copies the end-to-end fields of h into dst.
+func copyRequestHeaders(dst, h headers.Headers) {
+	hop := connectionTokens(h.Get("connection"))
+	for k, v := range h {
+		k = strings.ToLower(k)
+		if isHopHeader(k, hop) || k == "host" || k == "content-length" {
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// addForwardedHeaders appends the client to X-Forwarded-For and Forwarded
+// and records the original host and protocol.
+func addForwardedHeaders(dst headers.Headers, req *request.Request) {
+	clientIP, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		clientIP = req.RemoteAddr
+	}
+	host := req.Headers.Get("host")
+	if clientIP != "" {
+		if prior := dst.Get("x-forwarded-for"); prior != "" {
+			dst["x-forwarded-for"] = prior + ", " + clientIP
+		} else {
+			dst["x-forwarded-for"] = clientIP
+		}
+	}
+	if host != "" {
+		dst["x-forwarded-host"] = host
+	}
+	dst["x-forwarded-proto"] = "http"
+
+	// RFC 7239: IPv6 addresses are bracketed and quoted.
+	forwarded := "proto=http"
+	if clientIP != "" {
+		node := clientIP
+		if strings.Contains(node, ":") {
+			node = `"[` + node + `]"`
+		}
+		forwarded = "for=" + node + ";" + forwarded
+	}
+	if host != "" {
+		forwarded += ";host=" + strconv.Quote(host)
+	}
+	if prior := dst.Get("forwarded"); prior != "" {
+		forwarded = prior + ", " + forwarded
+	}
+	dst["forwarded"] = forwarded
+}
+
+// copyResponse relays resp to the client: the status code and end-to-end
+// headers as is, the body streamed with the upstream's Content-Length or,
+// if unknown, chunked with any upstream trailers.
+func copyResponse(w *response.Writer, req *request.Request, resp *response.Response) error {
+	h := headers.NewHeaders()
+	hop := connectionTokens(resp.Headers.Get("connection"))
+	for k, v := range resp.Headers {
+		// Set-Cookie lines are relayed one by one below.
+		if isHopHeader(k, hop) || k == "content-length" || k == "set-cookie" {
+			continue
+		}
+		h[k] = v
+	}
+	h.Set("Connection", "close")
+	for _, c := range resp.SetCookies {
+		if err := w.SetRawCookie(c); err != nil {
+			return err
+		}
+	}
+
+	code := resp.StatusLine.StatusCode
+	bodyless := req.RequestLine.Method == "HEAD" ||
+		code == response.StatusNoContent ||
+		code == response.StatusNotModified ||
+		(code >= 100 && code < 200)
+	chunked := !bodyless && resp.ContentLength < 0
+	if chunked {
+		h.Set("Transfer-Encoding", "chunked")
+		if trailer := resp.Headers.Get("trailer"); trailer != "" {
+			h.Set("Trailer", trailer)
+		}
+	} else if cl := resp.Headers.Get("content-length"); cl != "" {
+		h.Set("Content-Length", cl)
+	}
+
+	if err := w.WriteStatusLine(code); err != nil {
+		return err
+	}
+	if err := w.WriteHeaders(h); err != nil {
+		return err
+	}
+	if bodyless {
+		return nil
+	}
+	if !chunked {
+		_, err := w.WriteBodyFrom(resp.Body)
+		return err
+	}
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, werr := w.WriteChunkedBody(buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	w.WriteChunkedBodyDone()
+	return w.WriteTrailers(resp.Trailers)
+}
+
+func writeError(w *response.Writer, code response.StatusCode, err error) {
+	errH := server.HandlerError{Code: code, Message: err.Error() + "\n"}
+	errH.WriteError(w.Writer)
+}
+
+// writeStatus answers with code and its reason phrase only. Upstream
+// failures are answered this way, since their errors can name internal
+// addresses; callers log the error instead.
+func writeStatus(w *response.Writer, code response.StatusCode) {
+	errH := server.HandlerError{Code: code, Message: response.StatusText(code) + "\n"}
+	errH.WriteError(w.Writer)
+}
+
+// connectionTokens returns the field names listed in a Connection header,
+// which are hop-by-hop as well.
+func connectionTokens(connection string) []string {
+	var tokens []string
+	for _, t := range strings.Split(connection, ",") {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+func isHopHeader(name string, connectionTokens []string) bool {
+	return slices.Contains(hopHeaders, name) || slices.Contains(connectionTokens, name)
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
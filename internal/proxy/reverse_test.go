@@ -0,0 +1,151 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRequest(method, target, body string, fields map[string]string) *request.Request {
+	h := headers.NewHeaders()
+	for k, v := range fields {
+		h.Set(strings.ToLower(k), v)
+	}
+	return &request.Request{
+		RequestLine: request.RequestLine{Method: method, RequestTarget: target, HttpVersion: "1.1"},
+		Headers:     h,
+		Body:        []byte(body),
+		RemoteAddr:  "192.0.2.10:50000",
+	}
+}
+
+// serve runs h and parses what it wrote with net/http.
+func serve(t *testing.T, h func(*response.Writer, *request.Request), req *request.Request) *http.Response {
+	var buf bytes.Buffer
+	h(&response.Writer{Writer: &buf}, req)
+	resp, err := http.ReadResponse(bufio.NewReader(&buf), &http.Request{Method: req.RequestLine.Method})
+	require.NoError(t, err)
+	return resp
+}
+
+func TestReverseProxyForwardsRequest(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "PUT", r.Method)
+		assert.Equal(t, "/api/items/1", r.URL.Path)
+		assert.Equal(t, "x=1", r.URL.RawQuery)
+		assert.Equal(t, "payload", string(body))
+		assert.Equal(t, "yes", r.Header.Get("X-Custom"))
+		assert.Empty(t, r.Header.Get("X-Hop"))
+		assert.Empty(t, r.Header.Get("Keep-Alive"))
+		assert.Equal(t, "203.0.113.5, 192.0.2.10", r.Header.Get("X-Forwarded-For"))
+		assert.Equal(t, "example.com", r.Header.Get("X-Forwarded-Host"))
+		assert.Equal(t, "http", r.Header.Get("X-Forwarded-Proto"))
+		assert.Equal(t, `for=192.0.2.10;proto=http;host="example.com"`, r.Header.Get("Forwarded"))
+		w.Header().Set("X-Upstream", "1")
+		w.Header().Set("Keep-Alive", "timeout=5")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}))
+	defer upstream.Close()
+
+	p, err := NewReverseProxy(upstream.URL + "/api")
+	require.NoError(t, err)
+	req := newRequest("PUT", "/items/1?x=1", "payload", map[string]string{
+		"Host":            "example.com",
+		"X-Custom":        "yes",
+		"X-Hop":           "secret",
+		"Connection":      "X-Hop",
+		"Keep-Alive":      "timeout=5",
+		"X-Forwarded-For": "203.0.113.5",
+	})
+	resp := serve(t, p.Handle, req)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("X-Upstream"))
+	assert.Empty(t, resp.Header.Get("Keep-Alive"))
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "created", string(body))
+}
+
+func TestReverseProxyStreamsChunkedWithTrailers(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Checksum")
+		w.Write([]byte("hello "))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("world"))
+		w.Header().Set("X-Checksum", "abc")
+	}))
+	defer upstream.Close()
+
+	p, err := NewReverseProxy(upstream.URL)
+	require.NoError(t, err)
+	resp := serve(t, p.Handle, newRequest("GET", "/", "", nil))
+	assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "hello world", string(body))
+	assert.Equal(t, "abc", resp.Trailer.Get("X-Checksum"))
+}
+
+func TestReverseProxyKeepsSetCookieLines(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Set-Cookie", "a=1; Path=/")
+		w.Header().Add("Set-Cookie", "b=2; Expires=Wed, 21 Oct 2026 07:28:00 GMT")
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	p, err := NewReverseProxy(upstream.URL)
+	require.NoError(t, err)
+	resp := serve(t, p.Handle, newRequest("GET", "/", "", nil))
+	assert.Equal(t, []string{"a=1; Path=/", "b=2; Expires=Wed, 21 Oct 2026 07:28:00 GMT"}, resp.Header.Values("Set-Cookie"))
+	cookies := resp.Cookies()
+	require.Len(t, cookies, 2)
+	assert.Equal(t, "1", cookies[0].Value)
+	assert.Equal(t, "2", cookies[1].Value)
+}
+
+func TestReverseProxyUpstreamDown(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	upstream.Close()
+
+	p, err := NewReverseProxy(upstream.URL)
+	require.NoError(t, err)
+	resp := serve(t, p.Handle, newRequest("GET", "/", "", nil))
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	// Test: The dial error naming the upstream is not sent to the client
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "Bad Gateway\n", string(body))
+}
+
+func TestReverseProxyTimeout(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer upstream.Close()
+
+	p, err := NewReverseProxy(upstream.URL)
+	require.NoError(t, err)
+	p.Timeout = 20 * time.Millisecond
+	resp := serve(t, p.Handle, newRequest("GET", "/", "", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "Gateway Timeout\n", string(body))
+}
+
+func TestNewReverseProxyRejectsInvalidURL(t *testing.T) {
+	_, err := NewReverseProxy("localhost:8080")
+	require.Error(t, err)
+}
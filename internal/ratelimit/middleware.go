@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/danielNemeth19/http-protocol/internal/server"
+)
+
+// KeyFunc returns the key a request is rate limited by.
+type KeyFunc func(req *request.Request) string
+
+// KeyByIP limits each client IP separately.
+func KeyByIP(req *request.Request) string {
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		return host
+	}
+	return req.RemoteAddr
+}
+
+// KeyByHeader limits by the value of a header field, e.g. an API key.
+// Requests without it are limited by client IP.
+func KeyByHeader(name string) KeyFunc {
+	name = strings.ToLower(name)
+	return func(req *request.Request) string {
+		if v := req.Headers.Get(name); v != "" {
+			return name + "=" + v
+		}
+		return KeyByIP(req)
+	}
+}
+
+// KeyByRoute limits each method and path, without the query, separately.
+func KeyByRoute(req *request.Request) string {
+	path, _, _ := strings.Cut(req.RequestLine.RequestTarget, "?")
+	return req.RequestLine.Method + " " + path
+}
+
+// Keys combines key functions, e.g. to limit every client per route.
+func Keys(keys ...KeyFunc) KeyFunc {
+	return func(req *request.Request) string {
+		parts := make([]string, len(keys))
+		for i, key := range keys {
+			parts[i] = key(req)
+		}
+		return strings.Join(parts, "|")
+	}
+}
+
+// ParseKey returns the KeyFunc named "ip", "route" or "header:<name>".
+func ParseKey(s string) (KeyFunc, bool) {
+	switch s {
+	case "ip":
+		return KeyByIP, true
+	case "route":
+		return KeyByRoute, true
+	}
+	if name, found := strings.CutPrefix(s, "header:"); found && name != "" {
+		return KeyByHeader(name), true
+	}
+	return nil, false
+}
+
+// Middleware returns a server.Middleware limiting requests with l. Every
+// response carries the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset fields; denied requests get 429 Too Many Requests with
+// Retry-After.
+func Middleware(l Limiter, key KeyFunc) server.Middleware {
+	return func(next server.Handler) server.Handler {
+		return func(w *response.Writer, req *request.Request) {
+			res := l.Allow(key(req))
+			fields := map[string]string{
+				"RateLimit-Limit":     strconv.Itoa(res.Limit),
+				"RateLimit-Remaining": strconv.Itoa(res.Remaining),
+				"RateLimit-Reset":     seconds(res.Reset),
+			}
+			if res.Allowed {
+				for k, v := range fields {
+					w.AddHeader(k, v)
+				}
+				next(w, req)
+				return
+			}
+			msg := "Too many requests\n"
+			w.WriteStatusLine(response.StatusTooManyRequests)
+			h := response.GetDefaultHeaders(len(msg))
+			for k, v := range fields {
+				h[k] = v
+			}
+			h["Retry-After"] = seconds(res.RetryAfter)
+			w.WriteHeaders(h)
+			w.WriteBody([]byte(msg))
+		}
+	}
+}
+
+// seconds renders d as delay-seconds, rounded up so that clients do not
+// come back too early.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10)
+}
@@ -0,0 +1,163 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Result is a Limiter's decision on a single request.
+type Result struct {
+	Allowed bool
+	// Limit is the quota: the burst of a TokenBucket, the requests per
+	// window of a SlidingWindow.
+	Limit int
+	// Remaining is how much of the quota is left after this request.
+	Remaining int
+	// Reset is how long until the quota is fully available again.
+	Reset time.Duration
+	// RetryAfter is how long to wait before a denied request would be
+	// allowed; 0 if Allowed.
+	RetryAfter time.Duration
+}
+
+// Limiter decides whether a request identified by key may proceed.
+// Implementations keep their state in memory and forget keys that have
+// been idle long enough to be back at full quota.
+type Limiter interface {
+	Allow(key string) Result
+}
+
+// sweepInterval is how often the limiters look for idle keys to evict.
+const sweepInterval = time.Minute
+
+// TokenBucket allows bursts of up to Burst requests per key, refilled at
+// Rate requests per second.
+type TokenBucket struct {
+	rate  float64
+	burst int
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket returns a TokenBucket with the given refill rate per
+// second and burst size.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{rate: rate, burst: burst, now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+func (tb *TokenBucket) Allow(key string) Result {
+	now := tb.now()
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.sweep(now)
+
+	b := tb.buckets[key]
+	if b == nil {
+		b = &bucket{tokens: float64(tb.burst), last: now}
+		tb.buckets[key] = b
+	}
+	b.tokens = min(float64(tb.burst), b.tokens+now.Sub(b.last).Seconds()*tb.rate)
+	b.last = now
+
+	res := Result{Limit: tb.burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = tb.duration(1 - b.tokens)
+	}
+	res.Remaining = int(math.Floor(b.tokens))
+	res.Reset = tb.duration(float64(tb.burst) - b.tokens)
+	return res
+}
+
+// duration returns how long refilling n tokens takes.
+func (tb *TokenBucket) duration(n float64) time.Duration {
+	return time.Duration(math.Ceil(n / tb.rate * float64(time.Second)))
+}
+
+// sweep evicts buckets that have refilled completely, as they are
+// indistinguishable from new ones.
+func (tb *TokenBucket) sweep(now time.Time) {
+	if now.Sub(tb.lastSweep) < sweepInterval {
+		return
+	}
+	tb.lastSweep = now
+	full := tb.duration(float64(tb.burst))
+	for key, b := range tb.buckets {
+		if now.Sub(b.last) >= full {
+			delete(tb.buckets, key)
+		}
+	}
+}
+
+// SlidingWindow allows Limit requests per key within any Window long
+// period. It remembers the time of each allowed request, so its memory
+// use per key is proportional to Limit.
+type SlidingWindow struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	logs      map[string][]time.Time
+	lastSweep time.Time
+}
+
+// NewSlidingWindow returns a SlidingWindow allowing limit requests per
+// window.
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	return &SlidingWindow{limit: limit, window: window, now: time.Now, logs: make(map[string][]time.Time)}
+}
+
+func (sw *SlidingWindow) Allow(key string) Result {
+	now := sw.now()
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.sweep(now)
+
+	log := sw.expire(sw.logs[key], now)
+	res := Result{Limit: sw.limit}
+	if len(log) < sw.limit {
+		log = append(log, now)
+		res.Allowed = true
+	} else {
+		res.RetryAfter = log[0].Add(sw.window).Sub(now)
+	}
+	sw.logs[key] = log
+	res.Remaining = max(sw.limit-len(log), 0)
+	if len(log) > 0 {
+		res.Reset = log[len(log)-1].Add(sw.window).Sub(now)
+	}
+	return res
+}
+
+// expire drops the times that have left the window ending at now.
+func (sw *SlidingWindow) expire(log []time.Time, now time.Time) []time.Time {
+	i := 0
+	for i < len(log) && now.Sub(log[i]) >= sw.window {
+		i++
+	}
+	return log[i:]
+}
+
+func (sw *SlidingWindow) sweep(now time.Time) {
+	if now.Sub(sw.lastSweep) < sweepInterval {
+		return
+	}
+	sw.lastSweep = now
+	for key, log := range sw.logs {
+		if len(sw.expire(log, now)) == 0 {
+			delete(sw.logs, key)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock is a manually advanced time source.
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestTokenBucket(t *testing.T) {
+	c := &clock{t: time.Unix(1000, 0)}
+	tb := NewTokenBucket(2, 3)
+	tb.now = c.now
+
+	// Test: Burst is allowed, then denied until a token is refilled
+	for i := range 3 {
+		res := tb.Allow("a")
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 2-i, res.Remaining)
+	}
+	res := tb.Allow("a")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 3, res.Limit)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+	assert.Equal(t, 1500*time.Millisecond, res.Reset)
+
+	// Test: Other keys have their own bucket
+	assert.True(t, tb.Allow("b").Allowed)
+
+	c.advance(500 * time.Millisecond)
+	assert.True(t, tb.Allow("a").Allowed)
+	assert.False(t, tb.Allow("a").Allowed)
+
+	// Test: Full buckets are evicted
+	c.advance(2 * time.Minute)
+	tb.Allow("c")
+	assert.Len(t, tb.buckets, 1)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	c := &clock{t: time.Unix(1000, 0)}
+	sw := NewSlidingWindow(2, 10*time.Second)
+	sw.now = c.now
+
+	assert.True(t, sw.Allow("a").Allowed)
+	c.advance(4 * time.Second)
+	res := sw.Allow("a")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, 10*time.Second, res.Reset)
+
+	c.advance(4 * time.Second)
+	res = sw.Allow("a")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 2*time.Second, res.RetryAfter)
+
+	// Test: The first request leaves the window
+	c.advance(2 * time.Second)
+	assert.True(t, sw.Allow("a").Allowed)
+	assert.False(t, sw.Allow("a").Allowed)
+
+	// Test: Idle keys are evicted
+	c.advance(2 * time.Minute)
+	sw.Allow("b")
+	assert.Len(t, sw.logs, 1)
+}
+
+func newRequest(target, remoteAddr string, fields headers.Headers) *request.Request {
+	return &request.Request{
+		RequestLine: request.RequestLine{Method: "GET", RequestTarget: target, HttpVersion: "1.1"},
+		Headers:     fields,
+		RemoteAddr:  remoteAddr,
+	}
+}
+
+func TestKeys(t *testing.T) {
+	req := newRequest("/items?page=2", "192.0.2.10:50000", headers.Headers{"x-api-key": "secret"})
+	assert.Equal(t, "192.0.2.10", KeyByIP(req))
+	assert.Equal(t, "GET /items", KeyByRoute(req))
+	assert.Equal(t, "x-api-key=secret", KeyByHeader("X-Api-Key")(req))
+	assert.Equal(t, "192.0.2.10", KeyByHeader("X-Other")(req))
+	assert.Equal(t, "192.0.2.10|GET /items", Keys(KeyByIP, KeyByRoute)(req))
+
+	key, ok := ParseKey("header:X-Api-Key")
+	require.True(t, ok)
+	assert.Equal(t, "x-api-key=secret", key(req))
+	_, ok = ParseKey("cookie")
+	assert.False(t, ok)
+}
+
+func TestMiddleware(t *testing.T) {
+	h := Middleware(NewSlidingWindow(1, time.Minute), KeyByIP)(func(w *response.Writer, req *request.Request) {
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(response.GetDefaultHeaders(2))
+		w.WriteBody([]byte("ok"))
+	})
+	serve := func() *http.Response {
+		var buf bytes.Buffer
+		h(&response.Writer{Writer: &buf}, newRequest("/", "192.0.2.10:50000", headers.NewHeaders()))
+		resp, err := http.ReadResponse(bufio.NewReader(&buf), nil)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := serve()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("RateLimit-Limit"))
+	assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", resp.Header.Get("RateLimit-Reset"))
+
+	resp = serve()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "60", resp.Header.Get("Retry-After"))
+	assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+}
@@ -0,0 +1,50 @@
+package request
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Cookie is a name/value pair sent by the client in the Cookie header.
+type Cookie struct {
+	Name  string
+	Value string
+}
+
+// Cookies parses the Cookie header (RFC 6265 section 5.4). Pairs are
+// separated by ';', and also by ',' since repeated Cookie lines are merged
+// by headers.Headers.Set. Malformed pairs are skipped.
+func (r *Request) Cookies() []Cookie {
+	var cookies []Cookie
+	header := r.Headers.Get("cookie")
+	for _, pair := range strings.FieldsFunc(header, func(c rune) bool { return c == ';' || c == ',' }) {
+		name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || name == "" || !isToken(name) {
+			continue
+		}
+		if len(value) > 1 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+			value = value[1 : len(value)-1]
+		}
+		cookies = append(cookies, Cookie{Name: name, Value: value})
+	}
+	return cookies
+}
+
+// Cookie returns the first cookie with the given name.
+func (r *Request) Cookie(name string) (Cookie, error) {
+	for _, c := range r.Cookies() {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return Cookie{}, fmt.Errorf("Cookie not present: %s", name)
+}
+
+func isToken(s string) bool {
+	for _, c := range s {
+		if c <= ' ' || c >= 0x7f || strings.ContainsRune(`()<>@,;:\"/[]?={}`, c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package request
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestCookies(t *testing.T) {
+	reader := &chunkReader{
+		data:            "GET / HTTP/1.1\r\nCookie: session=abc123; theme=\"dark\"\r\nCookie: lang=hu\r\n\r\n",
+		numBytesPerRead: 5,
+	}
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	assert.Equal(t, []Cookie{
+		{Name: "session", Value: "abc123"},
+		{Name: "theme", Value: "dark"},
+		{Name: "lang", Value: "hu"},
+	}, r.Cookies())
+
+	c, err := r.Cookie("theme")
+	require.NoError(t, err)
+	assert.Equal(t, "dark", c.Value)
+
+	_, err = r.Cookie("missing")
+	require.EqualError(t, err, "Cookie not present: missing")
+}
+
+func TestRequestCookiesSkipsMalformed(t *testing.T) {
+	reader := &chunkReader{
+		data:            "GET / HTTP/1.1\r\nCookie: novalue; =empty; bad name=1; ok=\r\n\r\n",
+		numBytesPerRead: 50,
+	}
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	assert.Equal(t, []Cookie{{Name: "ok", Value: ""}}, r.Cookies())
+}
@@ -0,0 +1,38 @@
+package request
+
+// Kinds of ParseError, telling in which part of the request parsing
+// failed.
+const (
+	ErrorKindRequestLine = "request_line"
+	ErrorKindHeaders     = "headers"
+	ErrorKindBody        = "body"
+	// ErrorKindIncomplete means the stream ended before the request did.
+	ErrorKindIncomplete = "incomplete"
+	// ErrorKindRead means reading from the stream failed, e.g. timed out.
+	ErrorKindRead = "read"
+)
+
+// ParseError is returned by RequestFromReader when no request could be
+// read.
+type ParseError struct {
+	Kind string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func (s parseState) errorKind() string {
+	switch s {
+	case initialized:
+		return ErrorKindRequestLine
+	case requestStateParsingHeaders:
+		return ErrorKindHeaders
+	}
+	return ErrorKindBody
+}
@@ -2,6 +2,7 @@ package request
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"slices"
@@ -31,7 +32,11 @@ type Request struct {
 	RequestLine RequestLine
 	Headers     headers.Headers
 	Body        []byte
-	state       parseState
+	// RemoteAddr is the network address of the client, set by the server.
+	RemoteAddr string
+	state      parseState
+	buffered   []byte
+	ctx        context.Context
 }
 
 type RequestLine struct {
@@ -82,16 +87,17 @@ func (r *Request) parseSingle(data []byte) (int, error) {
 		if err != nil {
 			return 0, err
 		}
-		r.Body = append(r.Body, data...)
-		if len(r.Body) > length {
-			r.state = requestStateDone
-			return 0, fmt.Errorf("Length of body (%d) is greater then the Content-Length (%d)", len(r.Body), length)
+		if length < 0 {
+			return 0, fmt.Errorf("Invalid Content-Length: %d", length)
 		}
+		// Anything past Content-Length belongs to whatever follows the
+		// request on the connection, see Buffered.
+		chunk := data[:min(len(data), length-len(r.Body))]
+		r.Body = append(r.Body, chunk...)
 		if len(r.Body) == length {
 			r.state = requestStateDone
-			return len(data), nil
 		}
-		return len(data), nil
+		return len(chunk), nil
 	}
 	return 0, fmt.Errorf("Not sure what's going on")
 }
@@ -104,7 +110,7 @@ func (r *Request) parse(data []byte) (int, error) {
 	for r.state != requestStateDone {
 		n, err := r.parseSingle(data[totalParsed:])
 		if err != nil {
-			return 0, fmt.Errorf("Error during parsing: %s", err)
+			return 0, &ParseError{Kind: r.state.errorKind(), Err: fmt.Errorf("Error during parsing: %s", err)}
 		}
 		if n == 0 {
 			break
@@ -155,9 +161,9 @@ func RequestFromReader(reader io.Reader) (*Request, error) {
 		}
 		n, err := reader.Read(buf[readToIndex:])
 		if err == io.EOF {
-			return nil, fmt.Errorf("EOF hit before parsed request")
+			return nil, &ParseError{Kind: ErrorKindIncomplete, Err: fmt.Errorf("EOF hit before parsed request")}
 		} else if err != nil {
-			return nil, err
+			return nil, &ParseError{Kind: ErrorKindRead, Err: err}
 		}
 		readToIndex += n
 		parsedBytes, err := req.parse(buf[:readToIndex])
@@ -170,5 +176,35 @@ func RequestFromReader(reader io.Reader) (*Request, error) {
 			readToIndex = remainderBytes
 		}
 	}
+	if readToIndex > 0 {
+		req.buffered = append([]byte(nil), buf[:readToIndex]...)
+	}
 	return &req, nil
 }
+
+// Buffered returns the bytes RequestFromReader read from the stream past
+// the end of the request, e.g. the first bytes of a protocol the
+// connection is switched to.
+func (r *Request) Buffered() []byte {
+	return r.buffered
+}
+
+// Context returns the request's context. The server cancels it when the
+// client disconnects, the write timeout expires or the server shuts down.
+func (r *Request) Context() context.Context {
+	if r.ctx != nil {
+		return r.ctx
+	}
+	return context.Background()
+}
+
+// WithContext returns a shallow copy of r with its context changed to ctx,
+// e.g. to attach request-scoped values in a middleware.
+func (r *Request) WithContext(ctx context.Context) *Request {
+	if ctx == nil {
+		panic("nil context")
+	}
+	r2 := *r
+	r2.ctx = ctx
+	return &r2
+}
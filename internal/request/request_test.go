@@ -1,6 +1,7 @@
 package request
 
 import (
+	"context"
 	"io"
 	"testing"
 
@@ -246,3 +247,57 @@ func TestRequestFromReader_NonContentLengthWithBody(t *testing.T) {
 	require.NotNil(t, r)
 	assert.Equal(t, "", string(r.Body))
 }
+
+func TestRequestFromReader_BufferedBytesAfterBody(t *testing.T) {
+	reader := &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Content-Length: 5\r\n" +
+			"\r\n" +
+			"helloGET /next",
+		numBytesPerRead: 100,
+	}
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, "hello", string(r.Body))
+	rest, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "GET /next", string(r.Buffered())+string(rest))
+}
+
+func TestRequestFromReader_BufferedBytesWithoutBody(t *testing.T) {
+	reader := &chunkReader{
+		data:            "GET /chat HTTP/1.1\r\nUpgrade: websocket\r\n\r\n\x81\x85",
+		numBytesPerRead: 100,
+	}
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, []byte("\x81\x85"), r.Buffered())
+}
+
+type ctxKey struct{}
+
+func TestRequestContext(t *testing.T) {
+	r := &Request{}
+	assert.Equal(t, context.Background(), r.Context())
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	r2 := r.WithContext(ctx)
+	assert.Equal(t, "value", r2.Context().Value(ctxKey{}))
+	assert.Nil(t, r.Context().Value(ctxKey{}))
+}
+
+func TestRequestFromReader_ParseErrorKind(t *testing.T) {
+	for data, kind := range map[string]string{
+		"GET / HTTP/1.0\r\n\r\n":                       ErrorKindRequestLine,
+		"GET / HTTP/1.1\r\nHost localhost\r\n\r\n":     ErrorKindHeaders,
+		"POST / HTTP/1.1\r\nContent-Length: x\r\n\r\n": ErrorKindBody,
+		"GET / HTTP/1.1\r\nHost: localhost\r\n":        ErrorKindIncomplete,
+	} {
+		_, err := RequestFromReader(&chunkReader{data: data, numBytesPerRead: 3})
+		var parseErr *ParseError
+		require.ErrorAs(t, err, &parseErr, data)
+		assert.Equal(t, kind, parseErr.Kind, data)
+	}
+}
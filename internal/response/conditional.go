@@ -0,0 +1,171 @@
+package response
+
+import (
+	"strings"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+)
+
+type condResult int
+
+const (
+	condNone condResult = iota
+	condTrue
+	condFalse
+)
+
+// CheckPreconditions evaluates If-Match, If-Unmodified-Since, If-None-Match
+// and If-Modified-Since in the order of RFC 9110 section 13.2.2 against the
+// current validators of the target resource. An empty etag or a zero
+// modtime means the resource does not have that validator. If a
+// precondition fails, a 304 or 412 response is written and true is
+// returned: the handler must not write anything else.
+func CheckPreconditions(w *Writer, req *request.Request, etag string, modtime time.Time) bool {
+	code, failed := evaluatePreconditions(req, etag, modtime)
+	if !failed {
+		return false
+	}
+	if code == StatusNotModified {
+		writeNotModified(w, etag, modtime)
+	} else {
+		writePlain(w, code, "Precondition Failed\n", nil)
+	}
+	return true
+}
+
+func evaluatePreconditions(req *request.Request, etag string, modtime time.Time) (StatusCode, bool) {
+	method := req.RequestLine.Method
+	safe := method == "GET" || method == "HEAD"
+
+	ch := checkIfMatch(req, etag)
+	if ch == condNone {
+		ch = checkIfUnmodifiedSince(req, modtime)
+	}
+	if ch == condFalse {
+		return StatusPreconditionFailed, true
+	}
+
+	switch checkIfNoneMatch(req, etag) {
+	case condFalse:
+		if safe {
+			return StatusNotModified, true
+		}
+		return StatusPreconditionFailed, true
+	case condNone:
+		if safe && checkIfModifiedSince(req, modtime) == condFalse {
+			return StatusNotModified, true
+		}
+	}
+	return 0, false
+}
+
+func checkIfMatch(req *request.Request, etag string) condResult {
+	im := req.Headers.Get("if-match")
+	if im == "" {
+		return condNone
+	}
+	for _, tag := range splitETags(im) {
+		if tag == "*" || (etag != "" && etagStrongMatch(tag, etag)) {
+			return condTrue
+		}
+	}
+	return condFalse
+}
+
+func checkIfUnmodifiedSince(req *request.Request, modtime time.Time) condResult {
+	ius := req.Headers.Get("if-unmodified-since")
+	if ius == "" || modtime.IsZero() {
+		return condNone
+	}
+	t, err := time.Parse(TimeFormat, ius)
+	if err != nil {
+		return condNone
+	}
+	if modtime.UTC().Truncate(time.Second).After(t) {
+		return condFalse
+	}
+	return condTrue
+}
+
+func checkIfNoneMatch(req *request.Request, etag string) condResult {
+	inm := req.Headers.Get("if-none-match")
+	if inm == "" {
+		return condNone
+	}
+	for _, tag := range splitETags(inm) {
+		if tag == "*" || (etag != "" && etagWeakMatch(tag, etag)) {
+			return condFalse
+		}
+	}
+	return condTrue
+}
+
+func checkIfModifiedSince(req *request.Request, modtime time.Time) condResult {
+	ims := req.Headers.Get("if-modified-since")
+	if ims == "" || modtime.IsZero() {
+		return condNone
+	}
+	t, err := time.Parse(TimeFormat, ims)
+	if err != nil {
+		return condNone
+	}
+	if modtime.UTC().Truncate(time.Second).After(t) {
+		return condTrue
+	}
+	return condFalse
+}
+
+// splitETags splits a comma-separated list of entity tags. Commas inside
+// the quoted opaque-tag are kept.
+func splitETags(s string) []string {
+	var tags []string
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimLeft(s, " \t,") {
+		if s[0] == '*' {
+			tags = append(tags, "*")
+			s = s[1:]
+			continue
+		}
+		start := 0
+		if strings.HasPrefix(s, "W/") {
+			start = 2
+		}
+		if len(s) <= start || s[start] != '"' {
+			// Not an entity tag: skip to the next list member.
+			_, s, _ = strings.Cut(s, ",")
+			continue
+		}
+		end := strings.IndexByte(s[start+1:], '"')
+		if end == -1 {
+			break
+		}
+		end += start + 2
+		tags = append(tags, s[:end])
+		s = s[end:]
+	}
+	return tags
+}
+
+func etagStrongMatch(a, b string) bool {
+	return a == b && !strings.HasPrefix(a, "W/")
+}
+
+func etagWeakMatch(a, b string) bool {
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
+
+func writeNotModified(w *Writer, etag string, modtime time.Time) error {
+	h := headers.NewHeaders()
+	if etag != "" {
+		h.Set("ETag", etag)
+	}
+	if !modtime.IsZero() {
+		h.Set("Last-Modified", modtime.UTC().Format(TimeFormat))
+	}
+	h.Set("Connection", "close")
+	if err := w.WriteStatusLine(StatusNotModified); err != nil {
+		return err
+	}
+	return w.WriteHeaders(h)
+}
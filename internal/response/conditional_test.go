@@ -0,0 +1,113 @@
+package response
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var lastModified = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestSplitETags(t *testing.T) {
+	assert.Equal(t, []string{`"a"`, `W/"b"`, `"c,d"`}, splitETags(`"a", W/"b","c,d"`))
+	assert.Equal(t, []string{"*"}, splitETags("*"))
+	assert.Equal(t, []string{`"x"`}, splitETags(`bogus, "x"`))
+}
+
+func TestPreconditionsNoHeaders(t *testing.T) {
+	_, failed := evaluatePreconditions(newRequest("GET", nil), `"v1"`, lastModified)
+	assert.False(t, failed)
+}
+
+func TestPreconditionsIfNoneMatch(t *testing.T) {
+	code, failed := evaluatePreconditions(newRequest("GET", map[string]string{"If-None-Match": `W/"v1"`}), `"v1"`, lastModified)
+	assert.True(t, failed)
+	assert.Equal(t, StatusNotModified, code)
+
+	code, failed = evaluatePreconditions(newRequest("PUT", map[string]string{"If-None-Match": "*"}), `"v1"`, lastModified)
+	assert.True(t, failed)
+	assert.Equal(t, StatusPreconditionFailed, code)
+
+	_, failed = evaluatePreconditions(newRequest("GET", map[string]string{"If-None-Match": `"v2"`}), `"v1"`, lastModified)
+	assert.False(t, failed)
+}
+
+func TestPreconditionsIfMatch(t *testing.T) {
+	code, failed := evaluatePreconditions(newRequest("PUT", map[string]string{"If-Match": `"v2"`}), `"v1"`, lastModified)
+	assert.True(t, failed)
+	assert.Equal(t, StatusPreconditionFailed, code)
+
+	// Weak tags never match strongly.
+	_, failed = evaluatePreconditions(newRequest("PUT", map[string]string{"If-Match": `W/"v1"`}), `W/"v1"`, lastModified)
+	assert.True(t, failed)
+
+	_, failed = evaluatePreconditions(newRequest("PUT", map[string]string{"If-Match": `"v0", "v1"`}), `"v1"`, lastModified)
+	assert.False(t, failed)
+}
+
+func TestPreconditionsIfMatchTakesPrecedenceOverIfUnmodifiedSince(t *testing.T) {
+	req := newRequest("PUT", map[string]string{
+		"If-Match":            `"v1"`,
+		"If-Unmodified-Since": lastModified.Add(-time.Hour).Format(TimeFormat),
+	})
+	_, failed := evaluatePreconditions(req, `"v1"`, lastModified)
+	assert.False(t, failed)
+}
+
+func TestPreconditionsIfUnmodifiedSince(t *testing.T) {
+	req := newRequest("DELETE", map[string]string{"If-Unmodified-Since": lastModified.Add(-time.Hour).Format(TimeFormat)})
+	code, failed := evaluatePreconditions(req, "", lastModified)
+	assert.True(t, failed)
+	assert.Equal(t, StatusPreconditionFailed, code)
+}
+
+func TestPreconditionsIfModifiedSince(t *testing.T) {
+	req := newRequest("GET", map[string]string{"If-Modified-Since": lastModified.Format(TimeFormat)})
+	code, failed := evaluatePreconditions(req, "", lastModified.Add(500*time.Millisecond))
+	assert.True(t, failed)
+	assert.Equal(t, StatusNotModified, code)
+
+	req = newRequest("GET", map[string]string{"If-Modified-Since": lastModified.Add(-time.Hour).Format(TimeFormat)})
+	_, failed = evaluatePreconditions(req, "", lastModified)
+	assert.False(t, failed)
+
+	// If-None-Match makes If-Modified-Since irrelevant.
+	req = newRequest("GET", map[string]string{
+		"If-None-Match":     `"v2"`,
+		"If-Modified-Since": lastModified.Format(TimeFormat),
+	})
+	_, failed = evaluatePreconditions(req, `"v1"`, lastModified)
+	assert.False(t, failed)
+
+	// Only GET and HEAD use If-Modified-Since.
+	req = newRequest("POST", map[string]string{"If-Modified-Since": lastModified.Format(TimeFormat)})
+	_, failed = evaluatePreconditions(req, "", lastModified)
+	assert.False(t, failed)
+}
+
+func TestServeContentNotModified(t *testing.T) {
+	var buf bytes.Buffer
+	w := Writer{Writer: &buf}
+	req := newRequest("GET", map[string]string{"If-None-Match": `"v1"`})
+	err := ServeContent(&w, req, Content{Name: "a.txt", ETag: `"v1"`, ModTime: lastModified, Body: strings.NewReader("hello")})
+	require.NoError(t, err)
+	status, h, body := splitResponse(t, buf.String())
+	assert.Equal(t, "HTTP/1.1 304 Not Modified", status)
+	assert.Equal(t, `"v1"`, h.Get("etag"))
+	assert.Equal(t, "", body)
+}
+
+func TestServeContentIfRangeETag(t *testing.T) {
+	var buf bytes.Buffer
+	w := Writer{Writer: &buf}
+	req := newRequest("GET", map[string]string{"Range": "bytes=0-1", "If-Range": `"v1"`})
+	err := ServeContent(&w, req, Content{Name: "a.txt", ETag: `"v1"`, Body: strings.NewReader("hello")})
+	require.NoError(t, err)
+	status, _, body := splitResponse(t, buf.String())
+	assert.Equal(t, "HTTP/1.1 206 Partial Content", status)
+	assert.Equal(t, "he", body)
+}
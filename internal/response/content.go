@@ -0,0 +1,213 @@
+package response
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+)
+
+// TimeFormat is the IMF-fixdate format used in HTTP date fields.
+const TimeFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// Content describes a seekable representation served by ServeContent.
+type Content struct {
+	// Name is only used to derive the Content-Type from its extension.
+	Name    string
+	ModTime time.Time
+	// ETag is the quoted entity tag of the representation, if any.
+	ETag string
+	Body io.ReadSeeker
+}
+
+// ServeContent writes c as the response to req. Conditional headers are
+// evaluated first (see CheckPreconditions), then Range and If-Range: a
+// single satisfiable range is answered with 206 and a Content-Range,
+// several with multipart/byteranges, and unsatisfiable or malformed ones
+// with 416.
+func ServeContent(w *Writer, req *request.Request, c Content) error {
+	if CheckPreconditions(w, req, c.ETag, c.ModTime) {
+		return nil
+	}
+	size, err := c.Body.Seek(0, io.SeekEnd)
+	if err != nil {
+		return writePlain(w, StatusInternalServerError, "Cannot determine content size\n", nil)
+	}
+	ctype := contentType(c.Name)
+
+	h := headers.NewHeaders()
+	h.Set("Accept-Ranges", "bytes")
+	if c.ETag != "" {
+		h.Set("ETag", c.ETag)
+	}
+	if !c.ModTime.IsZero() {
+		h.Set("Last-Modified", c.ModTime.UTC().Format(TimeFormat))
+	}
+	h.Set("Connection", "close")
+
+	rangeHeader := req.Headers.Get("range")
+	if req.RequestLine.Method != "GET" || !checkIfRange(req, c) {
+		rangeHeader = ""
+	}
+	ranges, err := parseRange(rangeHeader, size)
+	if err != nil {
+		extra := headers.NewHeaders()
+		extra.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		return writePlain(w, StatusRangeNotSatisfiable, err.Error()+"\n", extra)
+	}
+	// A client asking for more bytes than the whole representation gets
+	// the whole representation instead.
+	if sumRangesSize(ranges) > size {
+		ranges = nil
+	}
+	bodyless := req.RequestLine.Method == "HEAD"
+
+	switch len(ranges) {
+	case 0:
+		h.Set("Content-Type", ctype)
+		h.Set("Content-Length", strconv.FormatInt(size, 10))
+		if err := w.WriteStatusLine(StatusOK); err != nil {
+			return err
+		}
+		if err := w.WriteHeaders(h); err != nil {
+			return err
+		}
+		if bodyless {
+			return nil
+		}
+		return w.copyBody(c.Body, 0, size)
+	case 1:
+		ra := ranges[0]
+		h.Set("Content-Type", ctype)
+		h.Set("Content-Range", ra.contentRange(size))
+		h.Set("Content-Length", strconv.FormatInt(ra.length, 10))
+		if err := w.WriteStatusLine(StatusPartialContent); err != nil {
+			return err
+		}
+		if err := w.WriteHeaders(h); err != nil {
+			return err
+		}
+		if bodyless {
+			return nil
+		}
+		return w.copyBody(c.Body, ra.start, ra.length)
+	default:
+		return w.writeMultipartRanges(h, c.Body, ctype, size, ranges, bodyless)
+	}
+}
+
+func (w *Writer) writeMultipartRanges(h headers.Headers, body io.ReadSeeker, ctype string, size int64, ranges []httpRange, bodyless bool) error {
+	partHeader := func(ra httpRange) textproto.MIMEHeader {
+		return textproto.MIMEHeader{
+			"Content-Type":  {ctype},
+			"Content-Range": {ra.contentRange(size)},
+		}
+	}
+	// Dry run with empty parts to learn the framing overhead, so the
+	// response can still carry a Content-Length.
+	counter := &countingWriter{}
+	mw := multipart.NewWriter(counter)
+	for _, ra := range ranges {
+		if _, err := mw.CreatePart(partHeader(ra)); err != nil {
+			return err
+		}
+	}
+	mw.Close()
+	length := counter.n + sumRangesSize(ranges)
+
+	h.Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+	h.Set("Content-Length", strconv.FormatInt(length, 10))
+	if err := w.WriteStatusLine(StatusPartialContent); err != nil {
+		return err
+	}
+	if err := w.WriteHeaders(h); err != nil {
+		return err
+	}
+	if bodyless {
+		return nil
+	}
+	pw := multipart.NewWriter(w.Writer)
+	pw.SetBoundary(mw.Boundary())
+	for _, ra := range ranges {
+		part, err := pw.CreatePart(partHeader(ra))
+		if err != nil {
+			return err
+		}
+		if _, err := body.Seek(ra.start, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(part, body, ra.length); err != nil {
+			return err
+		}
+	}
+	w.state = done
+	return pw.Close()
+}
+
+func (w *Writer) copyBody(body io.ReadSeeker, start, length int64) error {
+	if _, err := body.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := w.WriteBodyFrom(io.LimitReader(body, length))
+	return err
+}
+
+// checkIfRange reports whether the Range header may be honoured given the
+// If-Range precondition (RFC 9110 section 13.1.5).
+func checkIfRange(req *request.Request, c Content) bool {
+	ir := req.Headers.Get("if-range")
+	if ir == "" {
+		return true
+	}
+	if strings.HasPrefix(ir, `"`) || strings.HasPrefix(ir, "W/") {
+		return c.ETag != "" && etagStrongMatch(ir, c.ETag)
+	}
+	if c.ModTime.IsZero() {
+		return false
+	}
+	t, err := time.Parse(TimeFormat, ir)
+	if err != nil {
+		return false
+	}
+	return c.ModTime.UTC().Truncate(time.Second).Equal(t)
+}
+
+func contentType(name string) string {
+	ctype := mime.TypeByExtension(filepath.Ext(name))
+	if ctype == "" {
+		return "application/octet-stream"
+	}
+	return ctype
+}
+
+func writePlain(w *Writer, code StatusCode, message string, extra headers.Headers) error {
+	h := GetDefaultHeaders(len(message))
+	for k, v := range extra {
+		h.Set(k, v)
+	}
+	if err := w.WriteStatusLine(code); err != nil {
+		return err
+	}
+	if err := w.WriteHeaders(h); err != nil {
+		return err
+	}
+	_, err := w.WriteBody([]byte(message))
+	return err
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,128 @@
+package response
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SameSite int
+
+const (
+	SameSiteDefault SameSite = iota
+	SameSiteLax
+	SameSiteStrict
+	SameSiteNone
+)
+
+// Cookie is a cookie to be sent in a Set-Cookie header (RFC 6265 section
+// 4.1, plus the SameSite and Partitioned attributes).
+type Cookie struct {
+	Name    string
+	Value   string
+	Path    string
+	Domain  string
+	Expires time.Time
+	// MaxAge == 0 omits the attribute, MaxAge < 0 deletes the cookie
+	// (Max-Age=0).
+	MaxAge      int
+	Secure      bool
+	HttpOnly    bool
+	SameSite    SameSite
+	Partitioned bool
+}
+
+// Valid reports whether the cookie can be serialised as is.
+func (c *Cookie) Valid() error {
+	if c.Name == "" {
+		return fmt.Errorf("Cookie name is empty")
+	}
+	for _, r := range c.Name {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune(`()<>@,;:\"/[]?={}`, r) {
+			return fmt.Errorf("Invalid cookie name: %q", c.Name)
+		}
+	}
+	for _, r := range c.Value {
+		if r < 0x21 || r == '"' || r == ',' || r == ';' || r == '\\' || r >= 0x7f {
+			return fmt.Errorf("Invalid cookie value: %q", c.Value)
+		}
+	}
+	for _, attr := range []string{c.Path, c.Domain} {
+		if strings.ContainsAny(attr, ";\r\n") {
+			return fmt.Errorf("Invalid cookie attribute: %q", attr)
+		}
+	}
+	if c.SameSite == SameSiteNone && !c.Secure {
+		return fmt.Errorf("Cookie with SameSite=None must be Secure")
+	}
+	if c.Partitioned && !c.Secure {
+		return fmt.Errorf("Partitioned cookie must be Secure")
+	}
+	return nil
+}
+
+// String returns the Set-Cookie field value of the cookie.
+func (c *Cookie) String() string {
+	var b strings.Builder
+	b.WriteString(c.Name + "=" + c.Value)
+	if c.Path != "" {
+		b.WriteString("; Path=" + c.Path)
+	}
+	if c.Domain != "" {
+		b.WriteString("; Domain=" + strings.TrimPrefix(c.Domain, "."))
+	}
+	if !c.Expires.IsZero() {
+		b.WriteString("; Expires=" + c.Expires.UTC().Format(TimeFormat))
+	}
+	if c.MaxAge > 0 {
+		b.WriteString("; Max-Age=" + strconv.Itoa(c.MaxAge))
+	} else if c.MaxAge < 0 {
+		b.WriteString("; Max-Age=0")
+	}
+	if c.Secure {
+		b.WriteString("; Secure")
+	}
+	if c.HttpOnly {
+		b.WriteString("; HttpOnly")
+	}
+	switch c.SameSite {
+	case SameSiteLax:
+		b.WriteString("; SameSite=Lax")
+	case SameSiteStrict:
+		b.WriteString("; SameSite=Strict")
+	case SameSiteNone:
+		b.WriteString("; SameSite=None")
+	}
+	if c.Partitioned {
+		b.WriteString("; Partitioned")
+	}
+	return b.String()
+}
+
+// SetCookie queues a Set-Cookie header line. It has to be called before
+// WriteHeaders; every cookie is written on its own line, as Set-Cookie
+// values cannot be combined into one field.
+func (w *Writer) SetCookie(c *Cookie) error {
+	if w.state != initalized && w.state != writeStateHeaders {
+		return fmt.Errorf("Cookies must be set before the headers are written, got: %d", w.state)
+	}
+	if err := c.Valid(); err != nil {
+		return err
+	}
+	w.cookies = append(w.cookies, c.String())
+	return nil
+}
+
+// SetRawCookie queues a Set-Cookie header line with an already serialised
+// value, e.g. one relayed from an upstream response, which is sent as is.
+func (w *Writer) SetRawCookie(value string) error {
+	if w.state != initalized && w.state != writeStateHeaders {
+		return fmt.Errorf("Cookies must be set before the headers are written, got: %d", w.state)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("Invalid Set-Cookie value: %q", value)
+	}
+	w.cookies = append(w.cookies, value)
+	return nil
+}
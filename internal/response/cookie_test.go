@@ -0,0 +1,53 @@
+package response
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCookieString(t *testing.T) {
+	c := Cookie{
+		Name:        "session",
+		Value:       "abc123",
+		Path:        "/",
+		Domain:      ".example.com",
+		Expires:     time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+		MaxAge:      3600,
+		Secure:      true,
+		HttpOnly:    true,
+		SameSite:    SameSiteNone,
+		Partitioned: true,
+	}
+	require.NoError(t, c.Valid())
+	assert.Equal(t, "session=abc123; Path=/; Domain=example.com; Expires=Wed, 02 Jan 2030 03:04:05 GMT; Max-Age=3600; Secure; HttpOnly; SameSite=None; Partitioned", c.String())
+
+	c = Cookie{Name: "old", MaxAge: -1, SameSite: SameSiteLax}
+	assert.Equal(t, "old=; Max-Age=0; SameSite=Lax", c.String())
+}
+
+func TestCookieValid(t *testing.T) {
+	assert.Error(t, (&Cookie{Name: "a b", Value: "1"}).Valid())
+	assert.Error(t, (&Cookie{Name: "a", Value: "x;y"}).Valid())
+	assert.Error(t, (&Cookie{Name: "a", Path: "/;evil"}).Valid())
+	assert.Error(t, (&Cookie{Name: "a", SameSite: SameSiteNone}).Valid())
+	assert.Error(t, (&Cookie{Name: "a", Partitioned: true}).Valid())
+}
+
+func TestWriterSetCookieSeparateLines(t *testing.T) {
+	var buf bytes.Buffer
+	w := Writer{Writer: &buf}
+	require.NoError(t, w.SetCookie(&Cookie{Name: "a", Value: "1"}))
+	require.NoError(t, w.SetCookie(&Cookie{Name: "b", Value: "2", HttpOnly: true}))
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(headers.NewHeaders()))
+
+	out := buf.String()
+	assert.True(t, strings.HasSuffix(out, "Set-Cookie: a=1\r\nSet-Cookie: b=2; HttpOnly\r\n\r\n"))
+	assert.Error(t, w.SetCookie(&Cookie{Name: "c", Value: "3"}))
+}
@@ -0,0 +1,26 @@
+package response
+
+import (
+	"fmt"
+	"net"
+)
+
+// Hijacker is implemented by the io.Writer the server hands to handlers.
+// After Hijack the caller owns the connection: the server neither writes
+// to it nor closes it. buffered holds bytes already read from the
+// connection past the end of the request.
+type Hijacker interface {
+	Hijack() (conn net.Conn, buffered []byte, err error)
+}
+
+// Hijack takes over the underlying connection of the Writer. A Writer
+// wrapping a bare net.Conn hands that connection out as is.
+func (w *Writer) Hijack() (net.Conn, []byte, error) {
+	switch c := w.Writer.(type) {
+	case Hijacker:
+		return c.Hijack()
+	case net.Conn:
+		return c, nil, nil
+	}
+	return nil, nil, fmt.Errorf("Connection does not support hijacking")
+}
@@ -0,0 +1,344 @@
+package response
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+)
+
+type StatusLine struct {
+	HttpVersion  string
+	StatusCode   StatusCode
+	ReasonPhrase string
+}
+
+// Response is a parsed response. Unlike request.Request the body is not
+// read up front: it is streamed from the underlying reader.
+type Response struct {
+	StatusLine StatusLine
+	Headers    headers.Headers
+	Body       io.ReadCloser
+	// Trailers is filled once a chunked Body has been read to EOF.
+	Trailers headers.Headers
+	// ContentLength is -1 when the length is not known in advance.
+	ContentLength int64
+	// Close reports that the connection cannot carry another message
+	// after this one.
+	Close bool
+	// SetCookies holds the value of every Set-Cookie field line, in
+	// order. Headers joins them with "," like other repeated fields,
+	// which cannot be split back as cookie values may contain commas.
+	SetCookies []string
+	// Interim holds the 1xx responses (other than 101) received before
+	// the final one; they have no body.
+	Interim []*Response
+}
+
+// ResponseFromReader parses a response from reader. The body is framed by
+// Transfer-Encoding: chunked, by Content-Length, or else by the end of the
+// stream. Pass a *bufio.Reader to keep reading further messages from the
+// same stream once Body is consumed. Use ResponseFromReaderFor when the
+// response answers a HEAD request.
+func ResponseFromReader(reader io.Reader) (*Response, error) {
+	return ResponseFromReaderFor(reader, "")
+}
+
+// ResponseFromReaderFor parses the response to a request with the given
+// method. Interim 1xx responses are collected in Interim and the final
+// response is returned. Responses to HEAD and 1xx, 204 and 304 responses
+// never have a body, RFC 9112 section 6.3.
+func ResponseFromReaderFor(reader io.Reader, method string) (*Response, error) {
+	br, ok := reader.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(reader)
+	}
+	var interim []*Response
+	for {
+		resp, err := readHead(br)
+		if err != nil {
+			return nil, err
+		}
+		code := resp.StatusLine.StatusCode
+		if code >= 100 && code < 200 && code != StatusSwitchingProtocols {
+			resp.Body = emptyBody()
+			resp.ContentLength = 0
+			interim = append(interim, resp)
+			continue
+		}
+		resp.Interim = interim
+		if err := resp.setBody(br, method); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+func readHead(br *bufio.Reader) (*Response, error) {
+	line, err := readLine(br)
+	if err != nil {
+		return nil, err
+	}
+	statusLine, err := parseStatusLine(strings.TrimSuffix(string(line), "\r\n"))
+	if err != nil {
+		return nil, err
+	}
+	resp := &Response{
+		StatusLine: *statusLine,
+		Headers:    headers.NewHeaders(),
+		Trailers:   headers.NewHeaders(),
+	}
+	if err := parseFields(br, resp.Headers, &resp.SetCookies); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func parseStatusLine(line string) (*StatusLine, error) {
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("Malformed status line: %q", line)
+	}
+	version, found := strings.CutPrefix(parts[0], "HTTP/")
+	if !found || (version != "1.1" && version != "1.0") {
+		return nil, fmt.Errorf("HTTP Version is unsupported: %s", parts[0])
+	}
+	code, err := strconv.Atoi(parts[1])
+	if err != nil || len(parts[1]) != 3 {
+		return nil, fmt.Errorf("Invalid status code: %s", parts[1])
+	}
+	statusLine := StatusLine{HttpVersion: version, StatusCode: StatusCode(code)}
+	if len(parts) == 3 {
+		// The reason phrase is free text: any visible characters,
+		// spaces and tabs, but no other control characters.
+		for _, c := range parts[2] {
+			if (c < ' ' && c != '\t') || c == 0x7f {
+				return nil, fmt.Errorf("Invalid reason phrase: %q", parts[2])
+			}
+		}
+		statusLine.ReasonPhrase = parts[2]
+	}
+	return &statusLine, nil
+}
+
+// parseFields feeds CRLF-terminated lines to h.Parse until the empty line
+// ending the field section. Set-Cookie values are also appended to
+// cookies unless it is nil.
+func parseFields(br *bufio.Reader, h headers.Headers, cookies *[]string) error {
+	for {
+		line, err := readLine(br)
+		if err != nil {
+			return err
+		}
+		n, done, err := h.Parse(line)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		if n == 0 {
+			return fmt.Errorf("Malformed field line: %q", line)
+		}
+		name, value, _ := bytes.Cut(line[:n-2], []byte(":"))
+		if cookies != nil && strings.EqualFold(strings.TrimSpace(string(name)), "set-cookie") {
+			*cookies = append(*cookies, strings.TrimSpace(string(value)))
+		}
+	}
+}
+
+func (r *Response) setBody(br *bufio.Reader, method string) error {
+	r.ContentLength = -1
+	if connectionHas(r.Headers, "close") ||
+		(r.StatusLine.HttpVersion == "1.0" && !connectionHas(r.Headers, "keep-alive")) {
+		r.Close = true
+	}
+
+	code := r.StatusLine.StatusCode
+	switch {
+	case code == StatusSwitchingProtocols:
+		// The connection now speaks another protocol.
+		r.Close = true
+		r.ContentLength = 0
+		r.Body = emptyBody()
+		return nil
+	case code == StatusNoContent || code == StatusNotModified:
+		r.ContentLength = 0
+		r.Body = emptyBody()
+		return nil
+	case method == "HEAD":
+		// ContentLength reports the advertised length of the
+		// representation, even though no body follows.
+		if cl := r.Headers.Get("content-length"); cl != "" {
+			if length, err := parseContentLength(cl); err == nil {
+				r.ContentLength = length
+			}
+		}
+		r.Body = emptyBody()
+		return nil
+	}
+
+	if te := r.Headers.Get("transfer-encoding"); te != "" {
+		if r.Headers.Get("content-length") != "" {
+			// Transfer-Encoding wins, but the message may have been
+			// crafted for smuggling: do not reuse the connection.
+			r.Close = true
+		}
+		codings := strings.Split(te, ",")
+		if strings.EqualFold(strings.TrimSpace(codings[len(codings)-1]), "chunked") {
+			r.Body = io.NopCloser(&chunkedReader{br: br, trailers: r.Trailers})
+			return nil
+		}
+		// Not chunked last: the body runs until the connection closes,
+		// RFC 9112 section 6.3.
+		r.Close = true
+		r.Body = io.NopCloser(br)
+		return nil
+	}
+	if cl := r.Headers.Get("content-length"); cl != "" {
+		length, err := parseContentLength(cl)
+		if err != nil {
+			return err
+		}
+		r.ContentLength = length
+		r.Body = io.NopCloser(&lengthReader{r: br, remaining: length})
+		return nil
+	}
+	r.Close = true
+	r.Body = io.NopCloser(br)
+	return nil
+}
+
+// parseContentLength accepts repeated identical values, which
+// headers.Headers.Set joins with commas.
+func parseContentLength(value string) (int64, error) {
+	var length int64 = -1
+	for _, v := range strings.Split(value, ",") {
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil || n < 0 || (length != -1 && n != length) {
+			return 0, fmt.Errorf("Invalid Content-Length: %s", value)
+		}
+		length = n
+	}
+	return length, nil
+}
+
+func connectionHas(h headers.Headers, token string) bool {
+	for _, t := range strings.Split(h.Get("connection"), ",") {
+		if strings.EqualFold(strings.TrimSpace(t), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// readLine returns the next line including its CRLF.
+func readLine(br *bufio.Reader) ([]byte, error) {
+	line, err := br.ReadBytes('\n')
+	if err == io.EOF {
+		if len(line) == 0 {
+			return nil, io.EOF
+		}
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasSuffix(line, endLine) {
+		return nil, fmt.Errorf("Line not terminated by CRLF: %q", line)
+	}
+	return line, nil
+}
+
+var endLine = []byte("\r\n")
+
+// emptyBody is the empty body of bodyless responses.
+func emptyBody() io.ReadCloser {
+	return io.NopCloser(strings.NewReader(""))
+}
+
+type lengthReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *lengthReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if err == io.EOF && l.remaining > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// chunkedReader decodes a chunked body (RFC 9112 section 7.1), storing
+// the trailer section in trailers.
+type chunkedReader struct {
+	br        *bufio.Reader
+	trailers  headers.Headers
+	remaining int64
+	done      bool
+}
+
+var errMalformedChunk = errors.New("Malformed chunked encoding")
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if c.done {
+		return 0, io.EOF
+	}
+	if c.remaining == 0 {
+		line, err := readLine(c.br)
+		if err != nil {
+			return 0, noEOF(err)
+		}
+		sizeStr, _, _ := strings.Cut(strings.TrimSuffix(string(line), "\r\n"), ";")
+		size, err := strconv.ParseInt(strings.TrimSpace(sizeStr), 16, 64)
+		if err != nil || size < 0 {
+			return 0, errMalformedChunk
+		}
+		if size == 0 {
+			if err := parseFields(c.br, c.trailers, nil); err != nil {
+				return 0, noEOF(err)
+			}
+			c.done = true
+			return 0, io.EOF
+		}
+		c.remaining = size
+	}
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.br.Read(p)
+	c.remaining -= int64(n)
+	if c.remaining == 0 && err == nil {
+		line, lerr := readLine(c.br)
+		if lerr != nil {
+			return n, noEOF(lerr)
+		}
+		if len(line) != len(endLine) {
+			return n, errMalformedChunk
+		}
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
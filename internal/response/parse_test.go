@@ -0,0 +1,210 @@
+package response
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type chunkReader struct {
+	data            string
+	numBytesPerRead int
+	pos             int
+}
+
+func (cr *chunkReader) Read(p []byte) (n int, err error) {
+	if cr.pos >= len(cr.data) {
+		return 0, io.EOF
+	}
+	endIndex := min(cr.pos+cr.numBytesPerRead, len(cr.data))
+	n = copy(p, cr.data[cr.pos:endIndex])
+	cr.pos += n
+	return n, nil
+}
+
+func TestResponseFromReader_ContentLength(t *testing.T) {
+	reader := &chunkReader{
+		data:            "HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\nContent-Length: 13\r\n\r\nhello world!\nextra",
+		numBytesPerRead: 3,
+	}
+	r, err := ResponseFromReader(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "1.1", r.StatusLine.HttpVersion)
+	assert.Equal(t, StatusOK, r.StatusLine.StatusCode)
+	assert.Equal(t, "OK", r.StatusLine.ReasonPhrase)
+	assert.Equal(t, "text/plain", r.Headers.Get("content-type"))
+	assert.EqualValues(t, 13, r.ContentLength)
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world!\n", string(body))
+	assert.False(t, r.Close)
+}
+
+func TestResponseFromReader_SetCookies(t *testing.T) {
+	reader := &chunkReader{
+		data:            "HTTP/1.1 200 OK\r\nSet-Cookie: a=1; Path=/\r\nset-cookie:b=2; Expires=Wed, 21 Oct 2026 07:28:00 GMT\r\nContent-Length: 0\r\n\r\n",
+		numBytesPerRead: 5,
+	}
+	r, err := ResponseFromReader(reader)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a=1; Path=/", "b=2; Expires=Wed, 21 Oct 2026 07:28:00 GMT"}, r.SetCookies)
+
+	// Test: Written back one line each
+	var buf bytes.Buffer
+	w := Writer{Writer: &buf}
+	for _, c := range r.SetCookies {
+		require.NoError(t, w.SetRawCookie(c))
+	}
+	assert.Error(t, w.SetRawCookie("c=3\r\nX-Injected: 1"))
+	w.WriteStatusLine(StatusOK)
+	w.WriteHeaders(GetDefaultHeaders(0))
+	assert.Contains(t, buf.String(), "Set-Cookie: a=1; Path=/\r\nSet-Cookie: b=2; Expires=Wed, 21 Oct 2026 07:28:00 GMT\r\n")
+}
+
+func TestResponseFromReader_ShortContentLength(t *testing.T) {
+	reader := &chunkReader{
+		data:            "HTTP/1.1 200 OK\r\nContent-Length: 20\r\n\r\npartial",
+		numBytesPerRead: 4,
+	}
+	r, err := ResponseFromReader(reader)
+	require.NoError(t, err)
+	_, err = io.ReadAll(r.Body)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestResponseFromReader_Chunked(t *testing.T) {
+	reader := &chunkReader{
+		data: "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\nTrailer: X-Checksum\r\n\r\n" +
+			"5\r\nHello\r\n" +
+			"6;ext=1\r\n world\r\n" +
+			"0\r\n" +
+			"X-Checksum: abc123\r\n" +
+			"\r\n",
+		numBytesPerRead: 2,
+	}
+	r, err := ResponseFromReader(reader)
+	require.NoError(t, err)
+	assert.EqualValues(t, -1, r.ContentLength)
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello world", string(body))
+	assert.Equal(t, "abc123", r.Trailers.Get("x-checksum"))
+}
+
+func TestResponseFromReader_MalformedChunk(t *testing.T) {
+	reader := strings.NewReader("HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\nzz\r\n")
+	r, err := ResponseFromReader(reader)
+	require.NoError(t, err)
+	_, err = io.ReadAll(r.Body)
+	require.ErrorIs(t, err, errMalformedChunk)
+}
+
+func TestResponseFromReader_CloseDelimited(t *testing.T) {
+	reader := strings.NewReader("HTTP/1.1 200 OK\r\n\r\nuntil the connection closes")
+	r, err := ResponseFromReader(reader)
+	require.NoError(t, err)
+	assert.True(t, r.Close)
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "until the connection closes", string(body))
+}
+
+func TestResponseFromReader_InvalidStatusLine(t *testing.T) {
+	_, err := ResponseFromReader(strings.NewReader("HTTP/2.0 200 OK\r\n\r\n"))
+	require.EqualError(t, err, "HTTP Version is unsupported: HTTP/2.0")
+	_, err = ResponseFromReader(strings.NewReader("HTTP/1.1 2000 OK\r\n\r\n"))
+	require.EqualError(t, err, "Invalid status code: 2000")
+	_, err = ResponseFromReader(strings.NewReader("HTTP/1.1 200 OK\r\nContent-Length: 5, 6\r\n\r\n"))
+	require.EqualError(t, err, "Invalid Content-Length: 5, 6")
+}
+
+func TestResponseFromReader_ReasonPhrases(t *testing.T) {
+	r, err := ResponseFromReader(strings.NewReader("HTTP/1.1 404 Nothing to see here\r\nContent-Length: 0\r\n\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, StatusNotFound, r.StatusLine.StatusCode)
+	assert.Equal(t, "Nothing to see here", r.StatusLine.ReasonPhrase)
+
+	r, err = ResponseFromReader(strings.NewReader("HTTP/1.1 299 \r\nContent-Length: 0\r\n\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, StatusCode(299), r.StatusLine.StatusCode)
+	assert.Equal(t, "", r.StatusLine.ReasonPhrase)
+
+	r, err = ResponseFromReader(strings.NewReader("HTTP/1.0 200\r\nContent-Length: 0\r\n\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "", r.StatusLine.ReasonPhrase)
+	assert.True(t, r.Close)
+
+	_, err = ResponseFromReader(strings.NewReader("HTTP/1.1 200 O\x00K\r\n\r\n"))
+	require.Error(t, err)
+}
+
+func TestResponseFromReader_InterimResponses(t *testing.T) {
+	reader := &chunkReader{
+		data: "HTTP/1.1 100 Continue\r\n\r\n" +
+			"HTTP/1.1 103 Early Hints\r\nLink: </style.css>; rel=preload\r\n\r\n" +
+			"HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok",
+		numBytesPerRead: 7,
+	}
+	r, err := ResponseFromReader(reader)
+	require.NoError(t, err)
+	assert.Equal(t, StatusOK, r.StatusLine.StatusCode)
+	require.Len(t, r.Interim, 2)
+	assert.Equal(t, StatusCode(100), r.Interim[0].StatusLine.StatusCode)
+	assert.Equal(t, "</style.css>; rel=preload", r.Interim[1].Headers.Get("link"))
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(body))
+}
+
+func TestResponseFromReader_BodylessStatuses(t *testing.T) {
+	for _, status := range []string{"204 No Content", "304 Not Modified"} {
+		br := bufio.NewReader(strings.NewReader("HTTP/1.1 " + status + "\r\nContent-Length: 10\r\n\r\nHTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"))
+		r, err := ResponseFromReader(br)
+		require.NoError(t, err)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Empty(t, body)
+
+		// The next response on the stream is untouched.
+		next, err := ResponseFromReader(br)
+		require.NoError(t, err)
+		assert.Equal(t, StatusOK, next.StatusLine.StatusCode)
+	}
+}
+
+func TestResponseFromReader_Head(t *testing.T) {
+	br := bufio.NewReader(strings.NewReader("HTTP/1.1 200 OK\r\nContent-Length: 1234\r\n\r\nHTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"))
+	r, err := ResponseFromReaderFor(br, "HEAD")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1234, r.ContentLength)
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Empty(t, body)
+
+	next, err := ResponseFromReader(br)
+	require.NoError(t, err)
+	assert.Equal(t, StatusOK, next.StatusLine.StatusCode)
+}
+
+func TestResponseFromReader_SwitchingProtocols(t *testing.T) {
+	br := bufio.NewReader(strings.NewReader("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\n\r\n\x81\x02hi"))
+	r, err := ResponseFromReader(br)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSwitchingProtocols, r.StatusLine.StatusCode)
+	assert.True(t, r.Close)
+	assert.Equal(t, 4, br.Buffered())
+}
+
+func TestResponseFromReader_TransferEncodingOverridesContentLength(t *testing.T) {
+	r, err := ResponseFromReader(strings.NewReader("HTTP/1.1 200 OK\r\nContent-Length: 100\r\nTransfer-Encoding: chunked\r\n\r\n2\r\nok\r\n0\r\n\r\n"))
+	require.NoError(t, err)
+	assert.True(t, r.Close)
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(body))
+}
@@ -0,0 +1,133 @@
+package response
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var errNoOverlap = errors.New("Range does not overlap the content")
+
+// maxRanges is the most ranges a Range header may ask for; longer lists
+// are ignored, as RFC 9110 section 14.3 allows, rather than answered
+// with a multipart body of many tiny parts.
+const maxRanges = 32
+
+// httpRange is a resolved byte range: start offset and length in bytes.
+type httpRange struct {
+	start  int64
+	length int64
+}
+
+func (r httpRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, size)
+}
+
+// parseRange parses a Range header value (RFC 9110 section 14.1.2) against
+// a representation of the given size. Overlapping and adjacent ranges are
+// coalesced. A nil slice with no error means the header should be ignored
+// and the full representation served.
+func parseRange(s string, size int64) ([]httpRange, error) {
+	if s == "" {
+		return nil, nil
+	}
+	spec, found := strings.CutPrefix(s, "bytes=")
+	if !found {
+		// Unknown range units are ignored rather than rejected.
+		return nil, nil
+	}
+	var ranges []httpRange
+	noOverlap := false
+	for _, ra := range strings.Split(spec, ",") {
+		ra = strings.TrimSpace(ra)
+		if ra == "" {
+			continue
+		}
+		first, last, found := strings.Cut(ra, "-")
+		if !found {
+			return nil, fmt.Errorf("Invalid range: %s", ra)
+		}
+		first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+		var r httpRange
+		if first == "" {
+			// suffix-range: the final N bytes
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("Invalid range: %s", ra)
+			}
+			if n == 0 || size == 0 {
+				noOverlap = true
+				continue
+			}
+			n = min(n, size)
+			r.start = size - n
+			r.length = n
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, fmt.Errorf("Invalid range: %s", ra)
+			}
+			if start >= size {
+				noOverlap = true
+				continue
+			}
+			r.start = start
+			if last == "" {
+				r.length = size - start
+			} else {
+				end, err := strconv.ParseInt(last, 10, 64)
+				if err != nil || end < start {
+					return nil, fmt.Errorf("Invalid range: %s", ra)
+				}
+				end = min(end, size-1)
+				r.length = end - start + 1
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	if len(ranges) == 0 {
+		if noOverlap {
+			return nil, errNoOverlap
+		}
+		return nil, fmt.Errorf("Invalid range: %s", s)
+	}
+	ranges = coalesceRanges(ranges)
+	if len(ranges) > maxRanges {
+		return nil, nil
+	}
+	return ranges, nil
+}
+
+// coalesceRanges merges ranges that overlap or touch. Ranges are returned
+// in the order asked for unless some had to be merged, in which case they
+// come sorted by offset.
+func coalesceRanges(ranges []httpRange) []httpRange {
+	sorted := slices.Clone(ranges)
+	slices.SortFunc(sorted, func(a, b httpRange) int {
+		return cmp.Compare(a.start, b.start)
+	})
+	merged := sorted[:1]
+	for _, r := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if end := last.start + last.length; r.start <= end {
+			last.length = max(end, r.start+r.length) - last.start
+			continue
+		}
+		merged = append(merged, r)
+	}
+	if len(merged) == len(ranges) {
+		return ranges
+	}
+	return merged
+}
+
+func sumRangesSize(ranges []httpRange) int64 {
+	var size int64
+	for _, r := range ranges {
+		size += r.length
+	}
+	return size
+}
@@ -0,0 +1,202 @@
+package response
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRequest(method string, fields map[string]string) *request.Request {
+	h := headers.NewHeaders()
+	for k, v := range fields {
+		h.Set(strings.ToLower(k), v)
+	}
+	return &request.Request{
+		RequestLine: request.RequestLine{Method: method, RequestTarget: "/", HttpVersion: "1.1"},
+		Headers:     h,
+	}
+}
+
+func splitResponse(t *testing.T, raw string) (string, headers.Headers, string) {
+	statusLine, rest, found := strings.Cut(raw, "\r\n")
+	require.True(t, found)
+	h := headers.NewHeaders()
+	data := []byte(rest)
+	for {
+		n, done, err := h.Parse(data)
+		require.NoError(t, err)
+		data = data[n:]
+		if done {
+			break
+		}
+	}
+	return statusLine, h, string(data)
+}
+
+func TestParseRange(t *testing.T) {
+	ranges, err := parseRange("bytes=0-4", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []httpRange{{start: 0, length: 5}}, ranges)
+
+	ranges, err = parseRange("bytes=7-", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []httpRange{{start: 7, length: 3}}, ranges)
+
+	ranges, err = parseRange("bytes=-3", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []httpRange{{start: 7, length: 3}}, ranges)
+
+	ranges, err = parseRange("bytes=8-100", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []httpRange{{start: 8, length: 2}}, ranges)
+
+	ranges, err = parseRange("bytes=0-1, 4-5", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []httpRange{{start: 0, length: 2}, {start: 4, length: 2}}, ranges)
+
+	// Test: Overlapping and adjacent ranges are coalesced
+	ranges, err = parseRange("bytes=6-7, 0-2, 1-3, 4-5", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []httpRange{{start: 0, length: 8}}, ranges)
+	ranges, err = parseRange("bytes=6-7, 0-1", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []httpRange{{start: 6, length: 2}, {start: 0, length: 2}}, ranges)
+
+	// Test: Too many ranges are ignored
+	var many []string
+	for i := range maxRanges + 1 {
+		many = append(many, fmt.Sprintf("%d-%d", 2*i, 2*i))
+	}
+	ranges, err = parseRange("bytes="+strings.Join(many, ","), 100)
+	require.NoError(t, err)
+	assert.Nil(t, ranges)
+}
+
+func TestParseRangeIgnoresUnknownUnit(t *testing.T) {
+	ranges, err := parseRange("items=0-4", 10)
+	require.NoError(t, err)
+	assert.Nil(t, ranges)
+}
+
+func TestParseRangeInvalid(t *testing.T) {
+	_, err := parseRange("bytes=5-1", 10)
+	require.Error(t, err)
+	_, err = parseRange("bytes=abc", 10)
+	require.Error(t, err)
+	_, err = parseRange("bytes=10-", 10)
+	require.ErrorIs(t, err, errNoOverlap)
+	_, err = parseRange("bytes=-0", 10)
+	require.ErrorIs(t, err, errNoOverlap)
+	_, err = parseRange("bytes=-5", 0)
+	require.ErrorIs(t, err, errNoOverlap)
+}
+
+func TestServeContentFull(t *testing.T) {
+	var buf bytes.Buffer
+	w := Writer{Writer: &buf}
+	err := ServeContent(&w, newRequest("GET", nil), Content{Name: "a.txt", Body: strings.NewReader("hello world")})
+	require.NoError(t, err)
+	status, h, body := splitResponse(t, buf.String())
+	assert.Equal(t, "HTTP/1.1 200 OK", status)
+	assert.Equal(t, "11", h.Get("content-length"))
+	assert.Equal(t, "bytes", h.Get("accept-ranges"))
+	assert.Equal(t, "hello world", body)
+}
+
+func TestServeContentSingleRange(t *testing.T) {
+	var buf bytes.Buffer
+	w := Writer{Writer: &buf}
+	req := newRequest("GET", map[string]string{"Range": "bytes=6-"})
+	err := ServeContent(&w, req, Content{Name: "a.txt", Body: strings.NewReader("hello world")})
+	require.NoError(t, err)
+	status, h, body := splitResponse(t, buf.String())
+	assert.Equal(t, "HTTP/1.1 206 Partial Content", status)
+	assert.Equal(t, "bytes 6-10/11", h.Get("content-range"))
+	assert.Equal(t, "5", h.Get("content-length"))
+	assert.Equal(t, "world", body)
+}
+
+func TestServeContentMultipleRanges(t *testing.T) {
+	var buf bytes.Buffer
+	w := Writer{Writer: &buf}
+	req := newRequest("GET", map[string]string{"Range": "bytes=0-4,-5"})
+	err := ServeContent(&w, req, Content{Name: "a.txt", Body: strings.NewReader("hello world")})
+	require.NoError(t, err)
+	status, h, body := splitResponse(t, buf.String())
+	assert.Equal(t, "HTTP/1.1 206 Partial Content", status)
+	assert.Equal(t, strconv.Itoa(len(body)), h.Get("content-length"))
+
+	mediaType, params, err := mime.ParseMediaType(h.Get("content-type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/byteranges", mediaType)
+	mr := multipart.NewReader(strings.NewReader(body), params["boundary"])
+	var parts []string
+	var contentRanges []string
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(p)
+		require.NoError(t, err)
+		parts = append(parts, string(data))
+		contentRanges = append(contentRanges, p.Header.Get("Content-Range"))
+	}
+	assert.Equal(t, []string{"hello", "world"}, parts)
+	assert.Equal(t, []string{"bytes 0-4/11", "bytes 6-10/11"}, contentRanges)
+}
+
+func TestServeContentUnsatisfiableRange(t *testing.T) {
+	var buf bytes.Buffer
+	w := Writer{Writer: &buf}
+	req := newRequest("GET", map[string]string{"Range": "bytes=20-30"})
+	err := ServeContent(&w, req, Content{Name: "a.txt", Body: strings.NewReader("hello world")})
+	require.NoError(t, err)
+	status, h, _ := splitResponse(t, buf.String())
+	assert.Equal(t, "HTTP/1.1 416 Range Not Satisfiable", status)
+	assert.Equal(t, "bytes */11", h.Get("content-range"))
+
+	// Test: Suffix range of an empty representation
+	buf.Reset()
+	w = Writer{Writer: &buf}
+	req = newRequest("GET", map[string]string{"Range": "bytes=-5"})
+	err = ServeContent(&w, req, Content{Name: "a.txt", Body: strings.NewReader("")})
+	require.NoError(t, err)
+	status, h, _ = splitResponse(t, buf.String())
+	assert.Equal(t, "HTTP/1.1 416 Range Not Satisfiable", status)
+	assert.Equal(t, "bytes */0", h.Get("content-range"))
+}
+
+func TestServeContentIfRange(t *testing.T) {
+	modtime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var buf bytes.Buffer
+	w := Writer{Writer: &buf}
+	req := newRequest("GET", map[string]string{"Range": "bytes=0-4", "If-Range": modtime.Format(TimeFormat)})
+	err := ServeContent(&w, req, Content{Name: "a.txt", ModTime: modtime, Body: strings.NewReader("hello world")})
+	require.NoError(t, err)
+	status, _, body := splitResponse(t, buf.String())
+	assert.Equal(t, "HTTP/1.1 206 Partial Content", status)
+	assert.Equal(t, "hello", body)
+
+	buf.Reset()
+	w = Writer{Writer: &buf}
+	req = newRequest("GET", map[string]string{"Range": "bytes=0-4", "If-Range": modtime.Add(-time.Hour).Format(TimeFormat)})
+	err = ServeContent(&w, req, Content{Name: "a.txt", ModTime: modtime, Body: strings.NewReader("hello world")})
+	require.NoError(t, err)
+	status, _, body = splitResponse(t, buf.String())
+	assert.Equal(t, "HTTP/1.1 200 OK", status)
+	assert.Equal(t, "hello world", body)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 
 	"github.com/danielNemeth19/http-protocol/internal/headers"
 )
@@ -11,11 +12,58 @@ import (
 type StatusCode int
 
 const (
-	StatusOK                  StatusCode = 200
-	StatusBadRequest          StatusCode = 400
-	StatusInternalServerError StatusCode = 500
+	StatusSwitchingProtocols   StatusCode = 101
+	StatusOK                   StatusCode = 200
+	StatusNoContent            StatusCode = 204
+	StatusPartialContent       StatusCode = 206
+	StatusNotModified          StatusCode = 304
+	StatusBadRequest           StatusCode = 400
+	StatusUnauthorized         StatusCode = 401
+	StatusForbidden            StatusCode = 403
+	StatusNotFound             StatusCode = 404
+	StatusProxyAuthRequired    StatusCode = 407
+	StatusPreconditionFailed   StatusCode = 412
+	StatusContentTooLarge      StatusCode = 413
+	StatusUnsupportedMediaType StatusCode = 415
+	StatusRangeNotSatisfiable  StatusCode = 416
+	StatusMisdirectedRequest   StatusCode = 421
+	StatusUpgradeRequired      StatusCode = 426
+	StatusTooManyRequests      StatusCode = 429
+	StatusInternalServerError  StatusCode = 500
+	StatusBadGateway           StatusCode = 502
+	StatusServiceUnavailable   StatusCode = 503
+	StatusGatewayTimeout       StatusCode = 504
 )
 
+var statusText = map[StatusCode]string{
+	StatusSwitchingProtocols:   "Switching Protocols",
+	StatusOK:                   "OK",
+	StatusNoContent:            "No Content",
+	StatusPartialContent:       "Partial Content",
+	StatusNotModified:          "Not Modified",
+	StatusBadRequest:           "Bad Request",
+	StatusUnauthorized:         "Unauthorized",
+	StatusForbidden:            "Forbidden",
+	StatusNotFound:             "Not Found",
+	StatusProxyAuthRequired:    "Proxy Authentication Required",
+	StatusPreconditionFailed:   "Precondition Failed",
+	StatusContentTooLarge:      "Content Too Large",
+	StatusUnsupportedMediaType: "Unsupported Media Type",
+	StatusRangeNotSatisfiable:  "Range Not Satisfiable",
+	StatusMisdirectedRequest:   "Misdirected Request",
+	StatusUpgradeRequired:      "Upgrade Required",
+	StatusTooManyRequests:      "Too Many Requests",
+	StatusInternalServerError:  "Internal Server Error",
+	StatusBadGateway:           "Bad Gateway",
+	StatusServiceUnavailable:   "Service Unavailable",
+	StatusGatewayTimeout:       "Gateway Timeout",
+}
+
+// StatusText returns the reason phrase for the status code, or "" if unknown.
+func StatusText(code StatusCode) string {
+	return statusText[code]
+}
+
 var BadRequestHTML = `<html>
   <head>
     <title>400 Bad Request</title>
@@ -61,23 +109,50 @@ const (
 type Writer struct {
 	Writer io.Writer
 	state  writeState
+	// cookies holds the values of the Set-Cookie lines to write.
+	cookies []string
+	// extra holds fields added by AddHeader, keyed by lowercase name.
+	extra map[string]extraHeader
+}
+
+type extraHeader struct {
+	name  string
+	value string
+}
+
+// AddHeader queues a field to be sent by WriteHeaders along with the
+// handler's, so middleware can add fields to responses it does not write
+// itself. A field the handler sets as well is sent with the handler's
+// value only, except for Vary whose lists are combined. Adding the same
+// name again appends to the queued value with ", ", as headers.Set does,
+// so layers can each add to list fields such as Vary.
+func (w *Writer) AddHeader(name, value string) error {
+	if w.state != initalized && w.state != writeStateHeaders {
+		return fmt.Errorf("Headers must be added before they are written, got: %d", w.state)
+	}
+	if w.extra == nil {
+		w.extra = make(map[string]extraHeader)
+	}
+	key := strings.ToLower(name)
+	if field, ok := w.extra[key]; ok {
+		value = field.value + ", " + value
+		name = field.name
+	}
+	w.extra[key] = extraHeader{name, value}
+	return nil
 }
 
 func (w *Writer) WriteStatusLine(statusCode StatusCode) error {
 	if w.state != initalized {
 		return fmt.Errorf("Writer expected to be initialized, got: %d", w.state)
 	}
-	var statusLine string
-	switch statusCode {
-	case StatusOK:
-		statusLine = "HTTP/1.1 200 OK\r\n"
-	case StatusBadRequest:
-		statusLine = "HTTP/1.1 400 Bad Request\r\n"
-	case StatusInternalServerError:
-		statusLine = "HTTP/1.1 500 Internal Server Error\r\n"
-	default:
+	if statusCode < 100 || statusCode > 999 {
 		return fmt.Errorf("Unrecognized status code: %d\n", statusCode)
 	}
+	// Codes without a known reason phrase (e.g. relayed from an upstream)
+	// are sent with an empty one, which RFC 9112 section 4 allows.
+	reason := statusText[statusCode]
+	statusLine := fmt.Sprintf("HTTP/1.1 %d %s\r\n", statusCode, reason)
 	_, err := w.Writer.Write([]byte(statusLine))
 	if err != nil {
 		return err
@@ -91,9 +166,21 @@ func (w *Writer) WriteHeaders(headers headers.Headers) error {
 		return fmt.Errorf("Writer expected to be in writeStateHeaders, got: %d", w.state)
 	}
 	for k, v := range headers {
+		if vary, ok := w.extra["vary"]; ok && strings.EqualFold(k, "vary") {
+			v += ", " + vary.value
+		}
 		data := k + ": " + v + "\r\n"
 		w.Writer.Write([]byte(data))
 	}
+	for key, field := range w.extra {
+		if hasField(headers, key) {
+			continue
+		}
+		w.Writer.Write([]byte(field.name + ": " + field.value + "\r\n"))
+	}
+	for _, c := range w.cookies {
+		w.Writer.Write([]byte("Set-Cookie: " + c + "\r\n"))
+	}
 	w.Writer.Write([]byte("\r\n"))
 	w.state = writeStateBody
 	return nil
@@ -108,6 +195,24 @@ func (w *Writer) WriteBody(p []byte) (int, error) {
 	return len(p), nil
 }
 
+// WriteBodyPart writes part of a body whose length was announced with
+// Content-Length; unlike WriteBody it may be called repeatedly.
+func (w *Writer) WriteBodyPart(p []byte) (int, error) {
+	if w.state != writeStateBody {
+		return 0, fmt.Errorf("Writer expected to be in writeStateBody state, got: %d", w.state)
+	}
+	return w.Writer.Write(p)
+}
+
+// WriteBodyFrom copies r to the connection as the whole body.
+func (w *Writer) WriteBodyFrom(r io.Reader) (int64, error) {
+	if w.state != writeStateBody {
+		return 0, fmt.Errorf("Writer expected to be in writeStateBody state, got: %d", w.state)
+	}
+	w.state = done
+	return io.Copy(w.Writer, r)
+}
+
 func (w *Writer) WriteChunkedBody(p []byte) (int, error) {
 	if w.state != writeStateBody {
 		return 0, fmt.Errorf("Writer expected to be in writeStateBody state, got: %d", w.state)
@@ -147,6 +252,17 @@ func (w *Writer) WriteTrailers(h headers.Headers) error {
 	return nil
 }
 
+// hasField reports whether h contains the lowercase field name, in any
+// case.
+func hasField(h headers.Headers, name string) bool {
+	for k := range h {
+		if strings.ToLower(k) == name {
+			return true
+		}
+	}
+	return false
+}
+
 func GetDefaultHeaders(contentLen int) headers.Headers {
 	headers := headers.NewHeaders()
 	headers.Set("Content-Length", strconv.Itoa(contentLen))
@@ -0,0 +1,40 @@
+package response
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterAddHeader(t *testing.T) {
+	var buf bytes.Buffer
+	w := Writer{Writer: &buf}
+	require.NoError(t, w.AddHeader("X-Added", "first"))
+	require.NoError(t, w.AddHeader("x-added", "second"))
+	require.NoError(t, w.AddHeader("Content-Type", "application/json"))
+	require.NoError(t, w.AddHeader("Vary", "Origin"))
+	require.NoError(t, w.AddHeader("vary", "Accept-Encoding"))
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(headers.Headers{"Content-Type": "text/plain", "Vary": "Accept"}))
+	assert.Error(t, w.AddHeader("X-Late", "1"))
+
+	resp, err := ResponseFromReader(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "first, second", resp.Headers.Get("x-added"))
+	assert.Equal(t, "text/plain", resp.Headers.Get("content-type"))
+	assert.Equal(t, "Accept, Origin, Accept-Encoding", resp.Headers.Get("vary"))
+
+	// Test: Vary added by separate layers is combined without a handler's
+	buf.Reset()
+	w = Writer{Writer: &buf}
+	require.NoError(t, w.AddHeader("Vary", "Origin"))
+	require.NoError(t, w.AddHeader("Vary", "Accept"))
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(headers.Headers{"Content-Length": "0"}))
+	resp, err = ResponseFromReader(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "Origin, Accept", resp.Headers.Get("vary"))
+}
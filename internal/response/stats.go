@@ -0,0 +1,88 @@
+package response
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strconv"
+)
+
+// StatsWriter wraps the io.Writer of a Writer and records the status code
+// and body size of the response passing through it, for access logs and
+// metrics. It sees every byte, including responses written with
+// HandlerError, and passes Hijack through.
+type StatsWriter struct {
+	Writer io.Writer
+
+	head       []byte
+	headerDone bool
+	status     StatusCode
+	written    int64
+	hijacked   bool
+}
+
+// NewStatsWriter wraps the io.Writer of w in a StatsWriter and returns it.
+func NewStatsWriter(w *Writer) *StatsWriter {
+	s := &StatsWriter{Writer: w.Writer}
+	w.Writer = s
+	return s
+}
+
+func (s *StatsWriter) Write(p []byte) (int, error) {
+	n, err := s.Writer.Write(p)
+	if s.headerDone {
+		s.written += int64(n)
+		return n, err
+	}
+	s.head = append(s.head, p[:n]...)
+	if s.status == 0 {
+		if line, _, found := bytes.Cut(s.head, []byte("\r\n")); found {
+			s.status = parseStatus(line)
+		}
+	}
+	if i := bytes.Index(s.head, []byte("\r\n\r\n")); i >= 0 {
+		s.headerDone = true
+		s.written = int64(len(s.head) - i - 4)
+		s.head = nil
+	}
+	return n, err
+}
+
+// parseStatus returns the code of a status line, 0 if it is malformed.
+func parseStatus(line []byte) StatusCode {
+	fields := bytes.Fields(line)
+	if len(fields) < 2 {
+		return 0
+	}
+	code, err := strconv.Atoi(string(fields[1]))
+	if err != nil {
+		return 0
+	}
+	return StatusCode(code)
+}
+
+// Hijack takes over the connection of the wrapped writer. Bytes written to
+// the connection afterwards are not counted.
+func (s *StatsWriter) Hijack() (net.Conn, []byte, error) {
+	conn, buffered, err := (&Writer{Writer: s.Writer}).Hijack()
+	if err == nil {
+		s.hijacked = true
+	}
+	return conn, buffered, err
+}
+
+// Status returns the status code written so far, 0 if none.
+func (s *StatsWriter) Status() StatusCode {
+	return s.status
+}
+
+// BytesWritten returns the number of body bytes written, including any
+// chunked framing and trailers.
+func (s *StatsWriter) BytesWritten() int64 {
+	return s.written
+}
+
+// Hijacked reports whether the connection was taken over by the handler.
+func (s *StatsWriter) Hijacked() bool {
+	return s.hijacked
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+var ErrHijacked = errors.New("Connection has been hijacked")
+
+// aLongTimeAgo is a read deadline that makes a blocked Read return at once.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// conn is the io.Writer handed to handlers through response.Writer. It
+// implements response.Hijacker.
+type conn struct {
+	netConn  net.Conn
+	buffered []byte
+	mu       sync.Mutex
+	hijacked bool
+	metrics  *Metrics
+	// bgDone is closed when the background read started by
+	// watchDisconnect returns; nil if none was started.
+	bgDone chan struct{}
+}
+
+func (c *conn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hijacked {
+		return 0, ErrHijacked
+	}
+	n, err := c.netConn.Write(p)
+	if c.metrics != nil {
+		c.metrics.bytesOut.Add(float64(n))
+	}
+	return n, err
+}
+
+func (c *conn) Hijack() (net.Conn, []byte, error) {
+	c.abortBackgroundRead()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hijacked {
+		return nil, nil, ErrHijacked
+	}
+	c.hijacked = true
+	// The handler owns the connection from now on, including its
+	// deadlines.
+	c.netConn.SetDeadline(time.Time{})
+	buffered := c.buffered
+	c.buffered = nil
+	return c.netConn, buffered, nil
+}
+
+// watchDisconnect reads from the connection in the background while the
+// handler runs and calls cancel once the client closes it. A byte read
+// this way, e.g. the start of a pipelined request, is kept in buffered.
+func (c *conn) watchDisconnect(cancel context.CancelFunc) {
+	c.bgDone = make(chan struct{})
+	go func() {
+		defer close(c.bgDone)
+		var b [1]byte
+		n, err := c.netConn.Read(b[:])
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if n > 0 {
+			if c.metrics != nil {
+				c.metrics.bytesIn.Add(1)
+			}
+			c.buffered = append(c.buffered, b[0])
+			return
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			// Aborted by abortBackgroundRead.
+			return
+		}
+		cancel()
+	}()
+}
+
+// abortBackgroundRead stops the read started by watchDisconnect and waits
+// for it to return.
+func (c *conn) abortBackgroundRead() {
+	if c.bgDone == nil {
+		return
+	}
+	c.netConn.SetReadDeadline(aLongTimeAgo)
+	<-c.bgDone
+	c.bgDone = nil
+	c.netConn.SetReadDeadline(time.Time{})
+}
+
+func (c *conn) isHijacked() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hijacked
+}
+
+// close closes the connection unless a handler took it over.
+func (c *conn) close() {
+	if !c.isHijacked() {
+		c.netConn.Close()
+	}
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+)
+
+// FileServer returns a Handler serving the files under root. The request
+// target is cleaned before being joined to root, so it cannot escape it;
+// directories are served through their index.html. The ETag is derived
+// from the modification time and size of the file.
+func FileServer(root string) Handler {
+	return func(w *response.Writer, req *request.Request) {
+		target, _, _ := strings.Cut(req.RequestLine.RequestTarget, "?")
+		name := filepath.Join(root, filepath.FromSlash(path.Clean("/"+target)))
+
+		info, err := os.Stat(name)
+		if err == nil && info.IsDir() {
+			name = filepath.Join(name, "index.html")
+			info, err = os.Stat(name)
+		}
+		if err != nil || info.IsDir() {
+			errH := HandlerError{Code: response.StatusNotFound, Message: "File not found\n"}
+			errH.WriteError(w.Writer)
+			return
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			errH := HandlerError{Code: response.StatusInternalServerError, Message: err.Error()}
+			errH.WriteError(w.Writer)
+			return
+		}
+		defer f.Close()
+		response.ServeContent(w, req, response.Content{
+			Name:    info.Name(),
+			ModTime: info.ModTime(),
+			ETag:    fmt.Sprintf(`"%x-%x"`, info.ModTime().Unix(), info.Size()),
+			Body:    f,
+		})
+	}
+}
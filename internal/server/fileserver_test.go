@@ -0,0 +1,71 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveFile(t *testing.T, h Handler, target string) (int, string) {
+	req := &request.Request{
+		RequestLine: request.RequestLine{Method: "GET", RequestTarget: target, HttpVersion: "1.1"},
+		Headers:     headers.NewHeaders(),
+	}
+	var buf bytes.Buffer
+	h(&response.Writer{Writer: &buf}, req)
+	resp, err := http.ReadResponse(bufio.NewReader(&buf), nil)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestFileServer(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "site")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "docs"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "hello.txt"), []byte("hello"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "docs", "index.html"), []byte("<h1>docs</h1>"), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(root, "empty"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0o644))
+	h := FileServer(root)
+
+	code, body := serveFile(t, h, "/hello.txt?download=1")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "hello", body)
+
+	// Test: Directories are served through their index.html
+	code, body = serveFile(t, h, "/docs/")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "<h1>docs</h1>", body)
+	code, _ = serveFile(t, h, "/empty")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// Test: Missing files
+	code, _ = serveFile(t, h, "/missing.txt")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// Test: Targets cannot escape root
+	for _, target := range []string{
+		"/../secret.txt",
+		"/docs/../../secret.txt",
+		"/%2e%2e/secret.txt",
+		"/%2E%2E%2Fsecret.txt",
+		"/..%2fsecret.txt",
+		"../secret.txt",
+	} {
+		code, body = serveFile(t, h, target)
+		assert.Equal(t, http.StatusNotFound, code, target)
+		assert.NotContains(t, body, "secret", target)
+	}
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+)
+
+// StripPrefix returns a Handler that removes prefix from the request
+// target before calling h. Targets without the prefix get a 404.
+func StripPrefix(prefix string, h Handler) Handler {
+	return func(w *response.Writer, req *request.Request) {
+		rest, found := strings.CutPrefix(req.RequestLine.RequestTarget, prefix)
+		if !found {
+			errH := HandlerError{Code: response.StatusNotFound, Message: "Not found\n"}
+			errH.WriteError(w.Writer)
+			return
+		}
+		if !strings.HasPrefix(rest, "/") {
+			rest = "/" + rest
+		}
+		req.RequestLine.RequestTarget = rest
+		h(w, req)
+	}
+}
+
+// Middleware wraps a Handler to add behaviour around it.
+type Middleware func(Handler) Handler
+
+// Chain wraps h in the middlewares, the first one outermost.
+func Chain(h Handler, middlewares ...Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/response"
+)
+
+// ConnLimits bound how many connections a Server serves at once.
+type ConnLimits struct {
+	// MaxConnections caps the connections served at once; 0 means no
+	// limit.
+	MaxConnections int
+	// MaxPerIP caps the connections served at once for a single client
+	// IP; 0 means no limit.
+	MaxPerIP int
+	// Queue makes the server stop accepting while MaxConnections are
+	// served, leaving new clients waiting in the listen backlog, rather
+	// than answering them with 503. Clients over MaxPerIP always get a
+	// 503.
+	Queue bool
+	// RetryAfter is advertised in the Retry-After header of 503
+	// responses, rounded up to whole seconds; 0 means one second.
+	RetryAfter time.Duration
+}
+
+// WithConnLimits limits the connections the server serves at once.
+func WithConnLimits(l ConnLimits) Option {
+	return func(s *Server) {
+		s.limits = l
+		if l.MaxConnections > 0 {
+			s.slots = make(chan struct{}, l.MaxConnections)
+		}
+		s.perIP = make(map[string]int)
+	}
+}
+
+// Reasons a connection is rejected, as recorded in the metrics.
+const (
+	rejectMaxConnections = "max_connections"
+	rejectMaxPerIP       = "max_per_ip"
+)
+
+// rejectTimeout bounds how long answering a rejected connection may take.
+const rejectTimeout = time.Second
+
+// connLimiter is the state behind ConnLimits, embedded in Server.
+type connLimiter struct {
+	limits ConnLimits
+	// slots holds a token per connection served, nil without
+	// MaxConnections.
+	slots chan struct{}
+	mu    sync.Mutex
+	perIP map[string]int
+}
+
+// waitForSlot blocks until a connection may be accepted in queue mode.
+// It returns false if done is closed first.
+func (l *connLimiter) waitForSlot(done <-chan struct{}) bool {
+	if l.slots == nil || !l.limits.Queue {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// acquire reserves the resources to serve a connection from ip, whose
+// global slot is already held in queue mode. It returns the reason for
+// rejecting the connection, or "" if it may be served.
+func (l *connLimiter) acquire(ip string) string {
+	if l.slots != nil && !l.limits.Queue {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			return rejectMaxConnections
+		}
+	}
+	if l.limits.MaxPerIP > 0 {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.perIP[ip] >= l.limits.MaxPerIP {
+			l.releaseSlot()
+			return rejectMaxPerIP
+		}
+		l.perIP[ip]++
+	}
+	return ""
+}
+
+// release frees what acquire reserved for ip.
+func (l *connLimiter) release(ip string) {
+	if l.limits.MaxPerIP > 0 {
+		l.mu.Lock()
+		if l.perIP[ip]--; l.perIP[ip] <= 0 {
+			delete(l.perIP, ip)
+		}
+		l.mu.Unlock()
+	}
+	l.releaseSlot()
+}
+
+func (l *connLimiter) releaseSlot() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+func (l *connLimiter) limited() bool {
+	return l.slots != nil || l.limits.MaxPerIP > 0
+}
+
+// reject answers c with 503 and closes it. The request is drained after
+// the response so that closing does not reset the connection before the
+// client has read it.
+func (l *connLimiter) reject(c net.Conn) {
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(rejectTimeout))
+	retryAfter := int64((l.limits.RetryAfter + time.Second - 1) / time.Second)
+	if retryAfter <= 0 {
+		retryAfter = 1
+	}
+	msg := "Too many connections, try again later\n"
+	w := response.Writer{Writer: c}
+	w.WriteStatusLine(response.StatusServiceUnavailable)
+	h := response.GetDefaultHeaders(len(msg))
+	h.Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+	w.WriteHeaders(h)
+	w.WriteBody([]byte(msg))
+	if tcp, ok := c.(interface{ CloseWrite() error }); ok {
+		tcp.CloseWrite()
+	}
+	io.Copy(io.Discard, c)
+}
+
+// clientIP returns the IP part of a connection's remote address. Clients
+// of a Unix socket all share the empty IP.
+func clientIP(c net.Conn) string {
+	addr := remoteAddr(c)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/metrics"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingServer serves requests only once release is closed.
+func blockingServer(t *testing.T, started chan<- struct{}, release <-chan struct{}, opts ...Option) *Server {
+	s, err := Serve(0, func(w *response.Writer, req *request.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(response.GetDefaultHeaders(2))
+		w.WriteBody([]byte("ok"))
+	}, opts...)
+	require.NoError(t, err)
+	return s
+}
+
+func readStatus(t *testing.T, s *Server) (*http.Response, error) {
+	conn := dial(t, s)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err == nil {
+		io.ReadAll(resp.Body)
+	}
+	return resp, err
+}
+
+func TestConnLimitsReject(t *testing.T) {
+	for name, limits := range map[string]ConnLimits{
+		rejectMaxConnections: {MaxConnections: 1, RetryAfter: 1500 * time.Millisecond},
+		rejectMaxPerIP:       {MaxPerIP: 1, RetryAfter: 1500 * time.Millisecond},
+	} {
+		t.Run(name, func(t *testing.T) {
+			started, release := make(chan struct{}, 1), make(chan struct{})
+			m := NewMetrics(metrics.NewRegistry())
+			s := blockingServer(t, started, release, WithConnLimits(limits), WithMetrics(m))
+			defer s.Close()
+
+			first := make(chan *http.Response)
+			go func() {
+				resp, _ := readStatus(t, s)
+				first <- resp
+			}()
+			<-started
+
+			resp, err := readStatus(t, s)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+			assert.Equal(t, "2", resp.Header.Get("Retry-After"))
+			assert.Equal(t, float64(1), m.rejectedConnections.Value(name))
+
+			close(release)
+			assert.Equal(t, http.StatusOK, (<-first).StatusCode)
+
+			// The slot is free again.
+			assert.Eventually(t, func() bool {
+				resp, err := readStatus(t, s)
+				return err == nil && resp.StatusCode == http.StatusOK
+			}, time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+func TestConnLimitsQueue(t *testing.T) {
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	s := blockingServer(t, started, release, WithConnLimits(ConnLimits{MaxConnections: 1, Queue: true}))
+	defer s.Close()
+
+	results := make(chan int, 2)
+	for range 2 {
+		go func() {
+			resp, err := readStatus(t, s)
+			if err != nil {
+				results <- 0
+				return
+			}
+			results <- resp.StatusCode
+		}()
+	}
+	<-started
+	select {
+	case <-started:
+		t.Fatal("second connection served while the first one is")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	<-started
+	assert.Equal(t, http.StatusOK, <-results)
+	assert.Equal(t, http.StatusOK, <-results)
+}
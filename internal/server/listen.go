@@ -0,0 +1,106 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ListenUnix listens on the Unix domain socket at path and sets its file
+// permissions to mode, e.g. 0660 to restrict access to a group. A stale
+// socket left behind by a previous run is removed; one that still accepts
+// connections is an error. The socket file is removed on Close.
+func ListenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// listenFDsStart is the first file descriptor passed by systemd socket
+// activation, SD_LISTEN_FDS_START.
+const listenFDsStart = 3
+
+// SystemdListeners returns the listening sockets passed by systemd socket
+// activation (see sd_listen_fds(3)), in the order of the socket unit's
+// Listen directives. It returns no listeners and no error when the
+// process was not socket activated. The LISTEN_* variables are unset so
+// that child processes do not pick them up.
+func SystemdListeners() ([]net.Listener, error) {
+	return systemdListeners(listenFDsStart)
+}
+
+func systemdListeners(firstFD int) ([]net.Listener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+	return filesToListeners(firstFD, n, strings.Split(os.Getenv("LISTEN_FDNAMES"), ":"))
+}
+
+// filesToListeners turns the n inherited descriptors from firstFD on into
+// listeners.
+func filesToListeners(firstFD, n int, names []string) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, n)
+	for i := range n {
+		name := "LISTEN_FD_" + strconv.Itoa(firstFD+i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(firstFD+i), name)
+		// FileListener duplicates the descriptor, the original is
+		// closed either way.
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, errors.Join(fmt.Errorf("Inherited fd %d (%s) is not a listener", firstFD+i, name), err)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// remoteAddr returns the client address of c, empty if it has none as
+// for clients of a Unix socket.
+func remoteAddr(c net.Conn) string {
+	addr := c.RemoteAddr()
+	if addr == nil {
+		return ""
+	}
+	if ua, ok := addr.(*net.UnixAddr); ok && (ua == nil || ua.Name == "@") {
+		return ""
+	}
+	return addr.String()
+}
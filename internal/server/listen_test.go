@@ -0,0 +1,114 @@
+package server
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoRemoteAddr answers with the client address the server recorded.
+func echoRemoteAddr(w *response.Writer, req *request.Request) {
+	w.WriteStatusLine(response.StatusOK)
+	w.WriteHeaders(response.GetDefaultHeaders(len(req.RemoteAddr)))
+	w.WriteBody([]byte(req.RemoteAddr))
+}
+
+func get(t *testing.T, network, address string) string {
+	conn, err := net.Dial(network, address)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	resp, err := response.ResponseFromReader(conn)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestListenUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "http.sock")
+	l, err := ListenUnix(path, 0o660)
+	require.NoError(t, err)
+	s := ServeListener(l, echoRemoteAddr)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o660), info.Mode().Perm())
+	assert.Equal(t, "", get(t, "unix", path))
+
+	// Test: A socket in use is not taken over
+	_, err = ListenUnix(path, 0o660)
+	assert.Error(t, err)
+
+	s.Close()
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	// Test: A stale socket is replaced
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	l, err = ListenUnix(path, 0o600)
+	require.NoError(t, err)
+	l.Close()
+
+	// Test: Other files are left alone
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0o644))
+	_, err = ListenUnix(path, 0o600)
+	assert.Error(t, err)
+}
+
+func TestServeAddr(t *testing.T) {
+	s, err := ServeAddr("127.0.0.1:0", echoRemoteAddr)
+	require.NoError(t, err)
+	defer s.Close()
+	addr := s.Addr().(*net.TCPAddr)
+	assert.True(t, addr.IP.IsLoopback())
+	host, _, err := net.SplitHostPort(get(t, "tcp", addr.String()))
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", host)
+}
+
+func TestSystemdListeners(t *testing.T) {
+	// Test: Not socket activated
+	t.Setenv("LISTEN_PID", "1")
+	t.Setenv("LISTEN_FDS", "1")
+	listeners, err := SystemdListeners()
+	require.NoError(t, err)
+	assert.Empty(t, listeners)
+	assert.Empty(t, os.Getenv("LISTEN_FDS"))
+
+	// Test: Descriptors handed over as by systemd
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer tcp.Close()
+	f, err := tcp.(*net.TCPListener).File()
+	require.NoError(t, err)
+	// systemdListeners closes the descriptor, so hand it one f does not
+	// own.
+	fd, err := syscall.Dup(int(f.Fd()))
+	require.NoError(t, err)
+	f.Close()
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "1")
+	t.Setenv("LISTEN_FDNAMES", "http")
+	listeners, err = systemdListeners(fd)
+	require.NoError(t, err)
+	require.Len(t, listeners, 1)
+	assert.Empty(t, os.Getenv("LISTEN_PID"))
+
+	s := ServeListener(listeners[0], echoRemoteAddr)
+	defer s.Close()
+	assert.NotEmpty(t, get(t, "tcp", tcp.Addr().String()))
+}
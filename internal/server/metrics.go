@@ -0,0 +1,75 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/metrics"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+)
+
+// Metrics are the metrics a Server records when started WithMetrics.
+type Metrics struct {
+	requests            *metrics.Counter
+	duration            *metrics.Histogram
+	bytesIn             *metrics.Counter
+	bytesOut            *metrics.Counter
+	activeConnections   *metrics.Gauge
+	parseErrors         *metrics.Counter
+	rejectedConnections *metrics.Counter
+}
+
+// NewMetrics registers the server metrics in r.
+func NewMetrics(r *metrics.Registry) *Metrics {
+	return &Metrics{
+		requests: r.NewCounter("http_requests_total",
+			"Requests served, by method and status code.", "method", "status"),
+		duration: r.NewHistogram("http_request_duration_seconds",
+			"Time from the request being read to the handler returning.", nil, "method"),
+		bytesIn: r.NewCounter("http_received_bytes_total",
+			"Bytes read from client connections."),
+		bytesOut: r.NewCounter("http_sent_bytes_total",
+			"Bytes written to client connections, hijacked connections excluded."),
+		activeConnections: r.NewGauge("http_active_connections",
+			"Connections currently being served."),
+		parseErrors: r.NewCounter("http_request_parse_errors_total",
+			"Requests that could not be parsed, by kind.", "kind"),
+		rejectedConnections: r.NewCounter("http_rejected_connections_total",
+			"Connections answered with 503 because of connection limits, by reason.", "reason"),
+	}
+}
+
+// WithMetrics makes the server record m.
+func WithMetrics(m *Metrics) Option {
+	return func(s *Server) {
+		s.metrics = m
+	}
+}
+
+func (m *Metrics) observeRequest(method string, status int, start time.Time) {
+	m.requests.Inc(method, strconv.Itoa(status))
+	m.duration.Observe(time.Since(start).Seconds(), method)
+}
+
+func (m *Metrics) observeParseError(err error) {
+	kind := "unknown"
+	var parseErr *request.ParseError
+	if errors.As(err, &parseErr) {
+		kind = parseErr.Kind
+	}
+	m.parseErrors.Inc(kind)
+}
+
+// countingReader counts the bytes read from a client connection.
+type countingReader struct {
+	r io.Reader
+	m *Metrics
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.m.bytesIn.Add(float64(n))
+	return n, err
+}
@@ -0,0 +1,362 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+)
+
+// bufferedBodySize is how much body FromHTTPHandler holds back so that
+// small responses can be sent with a Content-Length rather than chunked.
+const bufferedBodySize = 4096
+
+// FromHTTPHandler runs a net/http Handler as a Handler. A body written
+// without a Content-Length is sent chunked once it outgrows a small
+// buffer or is flushed; trailers declared in the Trailer header or set
+// with http.TrailerPrefix follow the last chunk.
+func FromHTTPHandler(h http.Handler) Handler {
+	return func(w *response.Writer, req *request.Request) {
+		r, err := newHTTPRequest(req)
+		if err != nil {
+			errH := HandlerError{Code: response.StatusBadRequest, Message: err.Error() + "\n"}
+			errH.WriteError(w.Writer)
+			return
+		}
+		rw := &responseWriter{w: w, header: make(http.Header), head: r.Method == "HEAD"}
+		h.ServeHTTP(rw, r)
+		if err := rw.finish(); err != nil {
+			log.Printf("Writing net/http handler response failed: %v\n", err)
+		}
+	}
+}
+
+func newHTTPRequest(req *request.Request) (*http.Request, error) {
+	target := req.RequestLine.RequestTarget
+	u, err := url.ParseRequestURI(target)
+	if err != nil {
+		return nil, err
+	}
+	r := (&http.Request{
+		Method:        req.RequestLine.Method,
+		URL:           u,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          http.NoBody,
+		ContentLength: int64(len(req.Body)),
+		Host:          req.Headers.Get("host"),
+		RemoteAddr:    req.RemoteAddr,
+		RequestURI:    target,
+	}).WithContext(req.Context())
+	if len(req.Body) > 0 {
+		r.Body = io.NopCloser(bytes.NewReader(req.Body))
+	}
+	if r.Host == "" {
+		r.Host = u.Host
+	}
+	for k, v := range req.Headers {
+		if strings.EqualFold(k, "host") {
+			continue
+		}
+		r.Header[http.CanonicalHeaderKey(k)] = []string{v}
+	}
+	return r, nil
+}
+
+// responseWriter implements http.ResponseWriter, http.Flusher and
+// http.Hijacker on top of a response.Writer.
+type responseWriter struct {
+	w      *response.Writer
+	header http.Header
+	head   bool
+
+	status      int
+	wroteHeader bool
+	// headSent is set once the status line and headers are written;
+	// the body then continues chunked or, if the handler set a
+	// Content-Length, as is.
+	headSent bool
+	chunked  bool
+	hijacked bool
+	trailers []string
+	buf      []byte
+}
+
+func (rw *responseWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.wroteHeader || rw.hijacked {
+		return
+	}
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		// response.Writer has no way to send interim responses.
+		return
+	}
+	rw.status = code
+	rw.wroteHeader = true
+	for _, v := range rw.header.Values("Trailer") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				rw.trailers = append(rw.trailers, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	if rw.hijacked {
+		return 0, http.ErrHijacked
+	}
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.head || !bodyAllowed(rw.status) {
+		return len(p), nil
+	}
+	switch {
+	case rw.chunked:
+		return rw.w.WriteChunkedBody(p)
+	case rw.headSent:
+		return rw.w.WriteBodyPart(p)
+	}
+	rw.buf = append(rw.buf, p...)
+	if len(rw.buf) > bufferedBodySize {
+		if err := rw.sendHead(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (rw *responseWriter) Flush() {
+	if rw.hijacked {
+		return
+	}
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if !rw.headSent && !rw.head && bodyAllowed(rw.status) {
+		if err := rw.sendHead(); err != nil {
+			log.Printf("Flushing net/http handler response failed: %v\n", err)
+		}
+	}
+}
+
+// sendHead writes the status line, the headers and the buffered body.
+// Unless the handler set a Content-Length the body is chunked from here on.
+func (rw *responseWriter) sendHead() error {
+	rw.chunked = rw.header.Get("Content-Length") == "" || len(rw.trailers) > 0
+	if err := rw.writeHead(); err != nil {
+		return err
+	}
+	rw.headSent = true
+	buf := rw.buf
+	rw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if rw.chunked {
+		_, err = rw.w.WriteChunkedBody(buf)
+	} else {
+		_, err = rw.w.WriteBodyPart(buf)
+	}
+	return err
+}
+
+func (rw *responseWriter) writeHead() error {
+	h := headers.NewHeaders()
+	for k, vs := range rw.header {
+		switch {
+		case k == "Trailer" || k == "Connection" || k == "Transfer-Encoding" || k == "Set-Cookie":
+			continue
+		case strings.HasPrefix(k, http.TrailerPrefix):
+			continue
+		case k == "Content-Length" && rw.chunked:
+			continue
+		}
+		h[k] = strings.Join(vs, ", ")
+	}
+	if rw.chunked {
+		h["Transfer-Encoding"] = "chunked"
+		if len(rw.trailers) > 0 {
+			h["Trailer"] = strings.Join(rw.trailers, ", ")
+		}
+	}
+	if rw.status != http.StatusSwitchingProtocols {
+		h["Connection"] = "close"
+	}
+	// Set-Cookie lines are relayed as the handler wrote them; parsing them
+	// into a Cookie would drop attributes it has no field for.
+	for _, line := range rw.header.Values("Set-Cookie") {
+		if err := rw.w.SetRawCookie(line); err != nil {
+			log.Printf("Dropping invalid Set-Cookie from net/http handler: %v\n", err)
+		}
+	}
+	if err := rw.w.WriteStatusLine(response.StatusCode(rw.status)); err != nil {
+		return err
+	}
+	return rw.w.WriteHeaders(h)
+}
+
+// finish completes the response once the handler has returned.
+func (rw *responseWriter) finish() error {
+	if rw.hijacked {
+		return nil
+	}
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	bodyless := rw.head || !bodyAllowed(rw.status)
+	if !rw.headSent {
+		if bodyless || len(rw.trailers) == 0 {
+			if !bodyless && rw.header.Get("Content-Length") == "" {
+				rw.header.Set("Content-Length", strconv.Itoa(len(rw.buf)))
+			}
+			if err := rw.writeHead(); err != nil {
+				return err
+			}
+			if bodyless {
+				return nil
+			}
+			_, err := rw.w.WriteBody(rw.buf)
+			return err
+		}
+		if err := rw.sendHead(); err != nil {
+			return err
+		}
+	}
+	if !rw.chunked {
+		return nil
+	}
+	if _, err := rw.w.WriteChunkedBodyDone(); err != nil {
+		return err
+	}
+	trailers := headers.NewHeaders()
+	for _, name := range rw.trailers {
+		if vs := rw.header.Values(name); len(vs) > 0 {
+			trailers[name] = strings.Join(vs, ", ")
+		}
+	}
+	for k, vs := range rw.header {
+		if name, found := strings.CutPrefix(k, http.TrailerPrefix); found {
+			trailers[name] = strings.Join(vs, ", ")
+		}
+	}
+	return rw.w.WriteTrailers(trailers)
+}
+
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if rw.headSent {
+		return nil, nil, errors.New("Cannot hijack after the response was sent")
+	}
+	if rw.wroteHeader {
+		if rw.status != http.StatusSwitchingProtocols {
+			return nil, nil, fmt.Errorf("Cannot hijack after writing status %d", rw.status)
+		}
+		if err := rw.writeHead(); err != nil {
+			return nil, nil, err
+		}
+		rw.headSent = true
+	}
+	conn, buffered, err := rw.w.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	rw.hijacked = true
+	reader := bufio.NewReader(io.MultiReader(bytes.NewReader(buffered), conn))
+	return conn, bufio.NewReadWriter(reader, bufio.NewWriter(conn)), nil
+}
+
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// ToHTTPHandler runs a Handler as a net/http Handler. The bytes the
+// handler writes are parsed back into a response, so status, headers,
+// streamed or chunked bodies and trailers all carry over.
+func ToHTTPHandler(h Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := newRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		pr, pw := io.Pipe()
+		go func() {
+			writer := response.Writer{Writer: pw}
+			h(&writer, req)
+			pw.Close()
+		}()
+		defer pr.Close()
+
+		resp, err := response.ResponseFromReaderFor(pr, r.Method)
+		if err != nil {
+			log.Printf("Parsing handler response failed: %v\n", err)
+			http.Error(w, response.StatusText(response.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		defer resp.Body.Close()
+		for k, v := range resp.Headers {
+			k = http.CanonicalHeaderKey(k)
+			if k == "Connection" || k == "Transfer-Encoding" || k == "Set-Cookie" {
+				continue
+			}
+			w.Header().Set(k, v)
+		}
+		for _, c := range resp.SetCookies {
+			w.Header().Add("Set-Cookie", c)
+		}
+		w.WriteHeader(int(resp.StatusLine.StatusCode))
+		if _, err := io.Copy(w, resp.Body); err != nil {
+			log.Printf("Copying handler response failed: %v\n", err)
+			return
+		}
+		for k, v := range resp.Trailers {
+			w.Header().Set(http.TrailerPrefix+http.CanonicalHeaderKey(k), v)
+		}
+	})
+}
+
+func newRequest(r *http.Request) (*request.Request, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	req := (&request.Request{
+		RequestLine: request.RequestLine{
+			HttpVersion:   fmt.Sprintf("%d.%d", r.ProtoMajor, r.ProtoMinor),
+			RequestTarget: r.RequestURI,
+			Method:        r.Method,
+		},
+		Headers:    headers.NewHeaders(),
+		Body:       body,
+		RemoteAddr: r.RemoteAddr,
+	}).WithContext(r.Context())
+	if req.RequestLine.RequestTarget == "" {
+		req.RequestLine.RequestTarget = r.URL.RequestURI()
+	}
+	for k, vs := range r.Header {
+		req.Headers[strings.ToLower(k)] = strings.Join(vs, ",")
+	}
+	req.Headers["host"] = r.Host
+	if len(body) > 0 {
+		req.Headers["content-length"] = strconv.Itoa(len(body))
+	}
+	return req, nil
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveHTTP(t *testing.T, h http.Handler, method, target, body string) *http.Response {
+	req := &request.Request{
+		RequestLine: request.RequestLine{Method: method, RequestTarget: target, HttpVersion: "1.1"},
+		Headers:     headers.Headers{"host": "localhost:42069", "x-custom": "yes"},
+		Body:        []byte(body),
+		RemoteAddr:  "192.0.2.10:50000",
+	}
+	var buf bytes.Buffer
+	FromHTTPHandler(h)(&response.Writer{Writer: &buf}, req)
+	resp, err := http.ReadResponse(bufio.NewReader(&buf), &http.Request{Method: method})
+	require.NoError(t, err)
+	return resp
+}
+
+func TestFromHTTPHandler(t *testing.T) {
+	// Test: Small body is buffered and sent with Content-Length
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/items", r.URL.Path)
+		assert.Equal(t, "a=1", r.URL.RawQuery)
+		assert.Equal(t, "localhost:42069", r.Host)
+		assert.Equal(t, "yes", r.Header.Get("X-Custom"))
+		assert.Equal(t, "192.0.2.10:50000", r.RemoteAddr)
+		assert.Equal(t, "payload", string(body))
+		http.SetCookie(w, &http.Cookie{Name: "a", Value: "1"})
+		http.SetCookie(w, &http.Cookie{Name: "b", Value: "2"})
+		w.Header().Add("Set-Cookie", "c=1; SameSite=None")
+		w.Header().Add("Set-Cookie", "d=x y")
+		w.Header().Add("Set-Cookie", "e=3; Priority=High")
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	})
+	resp := serveHTTP(t, h, "POST", "/items?a=1", "payload")
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, int64(7), resp.ContentLength)
+	assert.Equal(t, "created", string(body))
+	assert.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+	// Test: Set-Cookie lines are relayed verbatim
+	assert.Equal(t, []string{"a=1", "b=2", "c=1; SameSite=None", "d=x y", "e=3; Priority=High"}, resp.Header.Values("Set-Cookie"))
+
+	// Test: Large body is streamed chunked
+	h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte("x"), 3000))
+		w.Write(bytes.Repeat([]byte("y"), 3000))
+	})
+	resp = serveHTTP(t, h, "GET", "/", "")
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+	assert.Equal(t, strings.Repeat("x", 3000)+strings.Repeat("y", 3000), string(body))
+
+	// Test: Flush and trailers
+	h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Checksum")
+		w.Write([]byte("part1"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("part2"))
+		w.Header().Set("X-Checksum", "abc")
+		w.Header().Set(http.TrailerPrefix+"X-Late", "late")
+	})
+	resp = serveHTTP(t, h, "GET", "/", "")
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "part1part2", string(body))
+	assert.Equal(t, "abc", resp.Trailer.Get("X-Checksum"))
+	assert.Equal(t, "late", resp.Trailer.Get("X-Late"))
+
+	// Test: Explicit Content-Length streams the body as is
+	h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "5000")
+		w.Write(bytes.Repeat([]byte("z"), 5000))
+	})
+	resp = serveHTTP(t, h, "GET", "/", "")
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Empty(t, resp.TransferEncoding)
+	assert.Equal(t, int64(5000), resp.ContentLength)
+	assert.Len(t, body, 5000)
+
+	// Test: HEAD discards the body, no body for 204
+	h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/empty" {
+			w.WriteHeader(http.StatusNoContent)
+		}
+		w.Write([]byte("ignored"))
+	})
+	resp = serveHTTP(t, h, "HEAD", "/", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = serveHTTP(t, h, "GET", "/empty", "")
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Empty(t, body)
+}
+
+func TestToHTTPHandler(t *testing.T) {
+	// Test: Handler served by net/http
+	h := func(w *response.Writer, req *request.Request) {
+		assert.Equal(t, "/hello?x=1", req.RequestLine.RequestTarget)
+		assert.Equal(t, "yes", req.Headers.Get("x-custom"))
+		assert.Equal(t, "payload", string(req.Body))
+		w.WriteStatusLine(response.StatusNotFound)
+		h := response.GetDefaultHeaders(len("missing"))
+		h.Set("X-Handler", "1")
+		w.WriteHeaders(h)
+		w.WriteBody([]byte("missing"))
+	}
+	srv := httptest.NewServer(ToHTTPHandler(h))
+	defer srv.Close()
+	req, err := http.NewRequest("POST", srv.URL+"/hello?x=1", strings.NewReader("payload"))
+	require.NoError(t, err)
+	req.Header.Set("X-Custom", "yes")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "missing", string(body))
+	assert.Equal(t, "1", resp.Header.Get("X-Handler"))
+
+	// Test: Chunked body with trailers
+	h = func(w *response.Writer, req *request.Request) {
+		w.WriteStatusLine(response.StatusOK)
+		h := response.GetChunkedHeaders()
+		h.Set("Trailer", "X-Checksum")
+		w.WriteHeaders(h)
+		w.WriteChunkedBody([]byte("hello "))
+		w.WriteChunkedBody([]byte("world"))
+		w.WriteChunkedBodyDone()
+		w.WriteTrailers(headers.Headers{"X-Checksum": "abc"})
+	}
+	rec := httptest.NewRecorder()
+	ToHTTPHandler(h).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "hello world", rec.Body.String())
+	assert.Equal(t, "abc", rec.Result().Trailer.Get("X-Checksum"))
+
+	// Test: Every cookie keeps its own Set-Cookie line
+	h = func(w *response.Writer, req *request.Request) {
+		w.SetCookie(&response.Cookie{Name: "a", Value: "1", Path: "/"})
+		w.SetCookie(&response.Cookie{Name: "b", Value: "2"})
+		w.SetRawCookie("c=3; Expires=Wed, 21 Oct 2026 07:28:00 GMT")
+		w.WriteStatusLine(response.StatusNoContent)
+		w.WriteHeaders(headers.NewHeaders())
+	}
+	rec = httptest.NewRecorder()
+	ToHTTPHandler(h).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, []string{"a=1; Path=/", "b=2", "c=3; Expires=Wed, 21 Oct 2026 07:28:00 GMT"}, rec.Header().Values("Set-Cookie"))
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 3)
+	assert.Equal(t, "1", cookies[0].Value)
+	assert.Equal(t, "2", cookies[1].Value)
+	assert.Equal(t, "3", cookies[2].Value)
+
+	// Test: Handler writing nothing gives 500
+	rec = httptest.NewRecorder()
+	ToHTTPHandler(func(w *response.Writer, req *request.Request) {}).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// WithProxyProtocol makes the server read the PROXY protocol header (v1 or
+// v2) that a load balancer sends ahead of the request, and report the
+// client address it carries as Request.RemoteAddr. Only connections from
+// the trusted networks, and over Unix sockets, must start with a header;
+// the others are served as is, so a client cannot forge its address.
+// Connection limits still count the balancer's address.
+func WithProxyProtocol(trusted []netip.Prefix) Option {
+	return func(s *Server) {
+		s.proxyProtocol = true
+		s.trustedProxies = trusted
+	}
+}
+
+// ParsePrefixes parses comma-separated networks in CIDR notation or single
+// IP addresses, e.g. "10.0.0.0/8,192.168.1.10".
+func ParsePrefixes(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if strings.Contains(field, "/") {
+			p, err := netip.ParsePrefix(field)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(field)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// sendsProxyHeader reports whether the peer of c must send a PROXY
+// protocol header.
+func (s *Server) sendsProxyHeader(c net.Conn) bool {
+	if !s.proxyProtocol {
+		return false
+	}
+	addr := remoteAddr(c)
+	if addr == "" {
+		return true
+	}
+	ap, err := netip.ParseAddrPort(addr)
+	if err != nil {
+		return false
+	}
+	ip := ap.Addr().Unmap()
+	for _, p := range s.trustedProxies {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	proxyV1Prefix    = []byte("PROXY ")
+	proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+// proxyV1MaxLen is the longest v1 header, CRLF included.
+const proxyV1MaxLen = 107
+
+// readProxyHeader reads a PROXY protocol header from r. It returns the
+// client address the header carries, "" if it carries none (v1 UNKNOWN,
+// v2 LOCAL or a Unix socket address), and the reader to continue with.
+func readProxyHeader(r io.Reader) (string, io.Reader, error) {
+	// Bytes past the header stay in br, sized so that they are at most a
+	// v1 header's worth.
+	br := bufio.NewReaderSize(r, proxyV1MaxLen+1)
+	sig, err := br.Peek(len(proxyV2Signature))
+	if err != nil {
+		return "", nil, fmt.Errorf("Reading PROXY header: %w", err)
+	}
+	var addr string
+	switch {
+	case bytes.HasPrefix(sig, proxyV1Prefix):
+		addr, err = readProxyV1(br)
+	case bytes.Equal(sig, proxyV2Signature):
+		addr, err = readProxyV2(br)
+	default:
+		err = errors.New("Connection does not start with a PROXY header")
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	rest, _ := br.Peek(br.Buffered())
+	return addr, io.MultiReader(bytes.NewReader(rest), r), nil
+}
+
+func readProxyV1(br *bufio.Reader) (string, error) {
+	line, err := br.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return "", errors.New("PROXY v1 header too long")
+	}
+	if err != nil {
+		return "", fmt.Errorf("Reading PROXY v1 header: %w", err)
+	}
+	header, found := bytes.CutSuffix(line, []byte("\r\n"))
+	if !found {
+		return "", errors.New("PROXY v1 header not terminated by CRLF")
+	}
+	parts := strings.Split(string(header), " ")
+	if len(parts) >= 2 && parts[1] == "UNKNOWN" {
+		return "", nil
+	}
+	if len(parts) != 6 {
+		return "", fmt.Errorf("PROXY v1 header supposed to have six parts, got: %d", len(parts))
+	}
+	var is4 bool
+	switch parts[1] {
+	case "TCP4":
+		is4 = true
+	case "TCP6":
+	default:
+		return "", fmt.Errorf("Unsupported PROXY v1 protocol: %s", parts[1])
+	}
+	src, err := netip.ParseAddr(parts[2])
+	if err != nil || src.Is4() != is4 {
+		return "", fmt.Errorf("Invalid PROXY v1 source address: %s", parts[2])
+	}
+	if dst, err := netip.ParseAddr(parts[3]); err != nil || dst.Is4() != is4 {
+		return "", fmt.Errorf("Invalid PROXY v1 destination address: %s", parts[3])
+	}
+	port, err := parsePort(parts[4])
+	if err != nil {
+		return "", err
+	}
+	if _, err := parsePort(parts[5]); err != nil {
+		return "", err
+	}
+	return netip.AddrPortFrom(src, port).String(), nil
+}
+
+// parsePort parses a v1 port: decimal without leading zeros.
+func parsePort(s string) (uint16, error) {
+	port, err := strconv.ParseUint(s, 10, 16)
+	if err != nil || (len(s) > 1 && s[0] == '0') {
+		return 0, fmt.Errorf("Invalid PROXY v1 port: %s", s)
+	}
+	return uint16(port), nil
+}
+
+// PROXY v2 commands and address families.
+const (
+	proxyV2Local = 0x0
+	proxyV2Proxy = 0x1
+
+	proxyV2Inet  = 0x1
+	proxyV2Inet6 = 0x2
+)
+
+func readProxyV2(br *bufio.Reader) (string, error) {
+	var fixed [16]byte
+	if _, err := io.ReadFull(br, fixed[:]); err != nil {
+		return "", fmt.Errorf("Reading PROXY v2 header: %w", err)
+	}
+	if version := fixed[12] >> 4; version != 2 {
+		return "", fmt.Errorf("Unsupported PROXY version: %d", version)
+	}
+	command, family := fixed[12]&0x0f, fixed[13]>>4
+	// The addresses are followed by TLVs, which are not used.
+	block := make([]byte, binary.BigEndian.Uint16(fixed[14:]))
+	if _, err := io.ReadFull(br, block); err != nil {
+		return "", fmt.Errorf("Reading PROXY v2 addresses: %w", err)
+	}
+	switch command {
+	case proxyV2Local:
+		return "", nil
+	case proxyV2Proxy:
+	default:
+		return "", fmt.Errorf("Unsupported PROXY v2 command: %d", command)
+	}
+	var size int
+	switch family {
+	case proxyV2Inet:
+		size = 4
+	case proxyV2Inet6:
+		size = 16
+	default:
+		// AF_UNSPEC and AF_UNIX carry no client address to report.
+		return "", nil
+	}
+	if len(block) < 2*size+4 {
+		return "", fmt.Errorf("PROXY v2 address block too short: %d", len(block))
+	}
+	src, _ := netip.AddrFromSlice(block[:size])
+	port := binary.BigEndian.Uint16(block[2*size:])
+	return netip.AddrPortFrom(src, port).String(), nil
+}
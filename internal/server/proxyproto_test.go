@@ -0,0 +1,129 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func proxyV2(command, family byte, addrs []byte) []byte {
+	b := append([]byte(nil), proxyV2Signature...)
+	b = append(b, 0x20|command, family<<4|0x1)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(addrs)))
+	return append(b, addrs...)
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	inet := []byte{203, 0, 113, 7, 10, 0, 0, 1, 0xd4, 0x31, 0x00, 0x50}
+	inet6 := append(netip.MustParseAddr("2001:db8::7").AsSlice(), netip.MustParseAddr("2001:db8::1").AsSlice()...)
+	inet6 = append(inet6, 0x01, 0xbb, 0x00, 0x50)
+	for _, tc := range []struct {
+		name   string
+		header []byte
+		addr   string
+	}{
+		{"v1 TCP4", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 54321 80\r\n"), "203.0.113.7:54321"},
+		{"v1 TCP6", []byte("PROXY TCP6 2001:db8::7 2001:db8::1 443 80\r\n"), "[2001:db8::7]:443"},
+		{"v1 UNKNOWN", []byte("PROXY UNKNOWN ff 01\r\n"), ""},
+		{"v2 INET", proxyV2(proxyV2Proxy, proxyV2Inet, inet), "203.0.113.7:54321"},
+		{"v2 INET6 with TLV", proxyV2(proxyV2Proxy, proxyV2Inet6, append(inet6, 0x04, 0x00, 0x01, 0x00)), "[2001:db8::7]:443"},
+		{"v2 LOCAL", proxyV2(proxyV2Local, 0, nil), ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			request := "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"
+			addr, rest, err := readProxyHeader(bytes.NewReader(append(tc.header, request...)))
+			require.NoError(t, err)
+			assert.Equal(t, tc.addr, addr)
+			data, err := io.ReadAll(rest)
+			require.NoError(t, err)
+			assert.Equal(t, request, string(data))
+		})
+	}
+
+	for _, tc := range []struct {
+		name   string
+		header []byte
+	}{
+		{"no header", []byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")},
+		{"v1 bad protocol", []byte("PROXY UDP4 203.0.113.7 10.0.0.1 54321 80\r\n")},
+		{"v1 family mismatch", []byte("PROXY TCP4 2001:db8::7 10.0.0.1 54321 80\r\n")},
+		{"v1 bad port", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 65536 80\r\n")},
+		{"v1 leading zero", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 080 80\r\n")},
+		{"v1 missing field", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 80\r\n")},
+		{"v1 bare LF", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 54321 80\n")},
+		{"v1 too long", append([]byte("PROXY TCP4 "), bytes.Repeat([]byte("1"), 120)...)},
+		{"v2 short block", proxyV2(proxyV2Proxy, proxyV2Inet6, inet)},
+		{"v2 bad command", proxyV2(0x2, proxyV2Inet, inet)},
+		{"v2 truncated", proxyV2(proxyV2Proxy, proxyV2Inet, inet)[:20]},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := readProxyHeader(bytes.NewReader(tc.header))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParsePrefixes(t *testing.T) {
+	prefixes, err := ParsePrefixes("10.0.0.0/8, 192.168.1.10,2001:db8::/32")
+	require.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.168.1.10/32"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}, prefixes)
+
+	_, err = ParsePrefixes("10.0.0.0/33")
+	assert.Error(t, err)
+	_, err = ParsePrefixes("localhost")
+	assert.Error(t, err)
+}
+
+func sendRaw(t *testing.T, addr string, data string) (string, error) {
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte(data))
+	require.NoError(t, err)
+	resp, err := response.ResponseFromReader(conn)
+	if err != nil {
+		return "", err
+	}
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestProxyProtocol(t *testing.T) {
+	request := "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"
+	header := "PROXY TCP4 203.0.113.7 10.0.0.1 54321 80\r\n"
+
+	s, err := ServeAddr("127.0.0.1:0", echoRemoteAddr, WithProxyProtocol([]netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}))
+	require.NoError(t, err)
+	defer s.Close()
+	body, err := sendRaw(t, s.Addr().String(), header+request)
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.7:54321", body)
+
+	// Test: A trusted peer must send a header
+	_, err = sendRaw(t, s.Addr().String(), request)
+	assert.Error(t, err)
+
+	// Test: The header of an untrusted peer is not taken for granted
+	s, err = ServeAddr("127.0.0.1:0", echoRemoteAddr, WithProxyProtocol([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}))
+	require.NoError(t, err)
+	defer s.Close()
+	body, err = sendRaw(t, s.Addr().String(), request)
+	require.NoError(t, err)
+	host, _, err := net.SplitHostPort(body)
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", host)
+	body, err = sendRaw(t, s.Addr().String(), header+request)
+	require.NoError(t, err)
+	assert.NotContains(t, body, "203.0.113.7")
+}
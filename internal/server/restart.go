@@ -0,0 +1,124 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Environment variables through which Restart hands listeners to the new
+// process.
+const (
+	listenFDsEnv = "HTTP_PROTOCOL_LISTEN_FDS"
+	readyFDEnv   = "HTTP_PROTOCOL_READY_FD"
+)
+
+// Restart starts a new instance of the running executable with the same
+// arguments and hands it listeners, which it picks up with
+// InheritedListeners. It returns once the new process called NotifyReady;
+// the caller then drains its own connections with Shutdown while the new
+// process accepts on the same sockets, so no connection is refused. If
+// the new process exits or is not ready within timeout, it is killed and
+// the caller keeps serving. It returns the pid of the new process.
+func Restart(listeners []net.Listener, timeout time.Duration) (int, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+	return startChild(path, os.Args, os.Environ(), listeners, timeout)
+}
+
+func startChild(path string, args, env []string, listeners []net.Listener, timeout time.Duration) (int, error) {
+	// The descriptors are passed as they are rather than through
+	// os/exec, whose File.Fd calls would switch the sockets, shared with
+	// our own listeners, to blocking mode.
+	fds := []uintptr{os.Stdin.Fd(), os.Stdout.Fd(), os.Stderr.Fd()}
+	for _, l := range listeners {
+		sc, ok := l.(syscall.Conn)
+		if !ok {
+			return 0, fmt.Errorf("Listener on %s cannot be handed over", l.Addr())
+		}
+		raw, err := sc.SyscallConn()
+		if err != nil {
+			return 0, err
+		}
+		raw.Control(func(fd uintptr) {
+			fds = append(fds, fd)
+		})
+	}
+	ready, readyW, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer ready.Close()
+	// The ready pipe comes last, after the listeners from descriptor 3.
+	fds = append(fds, readyW.Fd())
+	env = append(env,
+		listenFDsEnv+"="+strconv.Itoa(len(listeners)),
+		readyFDEnv+"="+strconv.Itoa(listenFDsStart+len(listeners)),
+	)
+	pid, err := syscall.ForkExec(path, args, &syscall.ProcAttr{Env: env, Files: fds})
+	// Only the child may hold the write end, so that its exit shows up
+	// as EOF.
+	readyW.Close()
+	if err != nil {
+		return 0, err
+	}
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return 0, err
+	}
+
+	ready.SetReadDeadline(time.Now().Add(timeout))
+	var b [1]byte
+	if _, err := io.ReadFull(ready, b[:]); err != nil {
+		proc.Kill()
+		proc.Wait()
+		if errors.Is(err, io.EOF) {
+			return 0, fmt.Errorf("New process exited before it was ready")
+		}
+		return 0, fmt.Errorf("New process not ready: %w", err)
+	}
+	// The sockets now belong to both processes; ours must not remove a
+	// Unix socket file from under the new one.
+	for _, l := range listeners {
+		if ul, ok := l.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+	// The new process outlives this one, nobody waits for it.
+	proc.Release()
+	return pid, nil
+}
+
+// InheritedListeners returns the listeners handed over by Restart in the
+// previous process, in the same order. It returns no listeners and no
+// error when the process was not started by Restart.
+func InheritedListeners() ([]net.Listener, error) {
+	n, err := strconv.Atoi(os.Getenv(listenFDsEnv))
+	os.Unsetenv(listenFDsEnv)
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+	return filesToListeners(listenFDsStart, n, nil)
+}
+
+// NotifyReady tells the process that started this one with Restart that
+// it is serving, upon which the old process starts draining. It does
+// nothing when the process was not started by Restart.
+func NotifyReady() error {
+	fd, err := strconv.Atoi(os.Getenv(readyFDEnv))
+	os.Unsetenv(readyFDEnv)
+	if err != nil {
+		return nil
+	}
+	f := os.NewFile(uintptr(fd), "ready")
+	defer f.Close()
+	_, err = f.Write([]byte{1})
+	return err
+}
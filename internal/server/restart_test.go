@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const restartChildEnv = "HTTP_PROTOCOL_TEST_RESTART_CHILD"
+
+func answer(body string) Handler {
+	return func(w *response.Writer, req *request.Request) {
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(response.GetDefaultHeaders(len(body)))
+		w.WriteBody([]byte(body))
+	}
+}
+
+// TestRestartChild is the new process started by TestRestart: it serves
+// the inherited listener until killed.
+func TestRestartChild(t *testing.T) {
+	mode := os.Getenv(restartChildEnv)
+	if mode == "" {
+		t.Skip("only run by TestRestart")
+	}
+	if mode == "fail" {
+		os.Exit(1)
+	}
+	listeners, err := InheritedListeners()
+	if err != nil || len(listeners) != 1 {
+		os.Exit(2)
+	}
+	ServeListener(listeners[0], answer("child"))
+	NotifyReady()
+	time.Sleep(time.Minute)
+	os.Exit(0)
+}
+
+func TestRestart(t *testing.T) {
+	s, err := ServeAddr("127.0.0.1:0", answer("parent"))
+	require.NoError(t, err)
+	addr := s.Addr().String()
+	assert.Equal(t, "parent", get(t, "tcp", addr))
+	args := []string{os.Args[0], "-test.run=^TestRestartChild$"}
+
+	// Test: A failing new process leaves the old one serving
+	_, err = startChild(os.Args[0], args, append(os.Environ(), restartChildEnv+"=fail"), []net.Listener{s.listener}, 5*time.Second)
+	assert.Error(t, err)
+	assert.Equal(t, "parent", get(t, "tcp", addr))
+
+	pid, err := startChild(os.Args[0], args, append(os.Environ(), restartChildEnv+"=serve"), []net.Listener{s.listener}, 5*time.Second)
+	require.NoError(t, err)
+	defer syscall.Kill(pid, syscall.SIGKILL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, s.Shutdown(ctx))
+	assert.Equal(t, "child", get(t, "tcp", addr))
+}
+
+func TestShutdownDrains(t *testing.T) {
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	s := blockingServer(t, started, release)
+	addr := s.Addr().String()
+	result := make(chan string)
+	go func() {
+		result <- get(t, "tcp", addr)
+	}()
+	<-started
+
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- s.Shutdown(context.Background())
+	}()
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown returned while a request was served")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	assert.Equal(t, "ok", <-result)
+	assert.NoError(t, <-shutdown)
+
+	// Test: Deadline cancels the remaining requests
+	started, result2 := make(chan struct{}), make(chan error, 1)
+	s, err := Serve(0, waitForCancel(started, result2))
+	require.NoError(t, err)
+	conn := dial(t, s)
+	defer conn.Close()
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.Shutdown(ctx), context.DeadlineExceeded)
+	assert.ErrorIs(t, <-result2, context.Canceled)
+}
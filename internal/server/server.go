@@ -1,11 +1,15 @@
 package server
 
 import (
+	"context"
 	"io"
 	"log"
 	"net"
+	"net/netip"
 	"strconv"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/danielNemeth19/http-protocol/internal/request"
 	"github.com/danielNemeth19/http-protocol/internal/response"
@@ -23,26 +27,101 @@ func (h HandlerError) WriteError(w io.Writer) {
 	writer.WriteStatusLine(h.Code)
 	headers := response.GetDefaultHeaders(len(h.Message))
 	writer.WriteHeaders(headers)
-	w.Write([]byte("\r\n"))
-	w.Write([]byte(h.Message))
+	writer.WriteBody([]byte(h.Message))
 }
 
 type Server struct {
 	listener   net.Listener
 	handler    Handler
 	inShutdown atomic.Bool
+	// quit is closed once the server stops accepting connections;
+	// listenDone once the accept loop has returned.
+	quit       chan struct{}
+	quitOnce   sync.Once
+	listenDone chan struct{}
+	// conns tracks the connections being served, for Shutdown.
+	conns sync.WaitGroup
+	// baseCtx is the parent of every request context; cancelBase
+	// cancels it on Close.
+	baseCtx    context.Context
+	cancelBase context.CancelFunc
+	// writeTimeout bounds the time between reading a request and the
+	// end of writing its response; 0 means no limit.
+	writeTimeout time.Duration
+	metrics      *Metrics
+	// proxyProtocol is set by WithProxyProtocol.
+	proxyProtocol  bool
+	trustedProxies []netip.Prefix
+	connLimiter
 }
 
+// Option configures a Server started by Serve.
+type Option func(*Server)
+
+// WithWriteTimeout limits how long a handler may take to write its
+// response. When it expires the request context is cancelled and further
+// writes fail.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.writeTimeout = d
+	}
+}
+
+// WithBaseContext makes ctx the parent of every request context, e.g. to
+// provide server-wide values.
+func WithBaseContext(ctx context.Context) Option {
+	return func(s *Server) {
+		s.baseCtx = ctx
+	}
+}
+
+// Close stops the server at once: it stops accepting connections and
+// cancels the context of every request being served.
 func (s *Server) Close() error {
-	s.inShutdown.Store(true)
-	s.listener.Close()
+	s.stopAccepting()
+	s.cancelBase()
 	return nil
 }
 
+// Shutdown stops accepting connections and waits for the ones being
+// served to finish. If ctx ends first, the context of every remaining
+// request is cancelled and ctx's error returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.stopAccepting()
+	defer s.cancelBase()
+	done := make(chan struct{})
+	go func() {
+		<-s.listenDone
+		s.conns.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Server) stopAccepting() {
+	s.inShutdown.Store(true)
+	s.quitOnce.Do(func() {
+		close(s.quit)
+		s.listener.Close()
+	})
+}
+
 func (s *Server) listen() {
+	defer close(s.listenDone)
 	for {
+		if !s.waitForSlot(s.quit) {
+			break
+		}
 		conn, err := s.listener.Accept()
 		if err != nil {
+			if s.slots != nil && s.limits.Queue {
+				s.releaseSlot()
+			}
 			isShutdown := s.inShutdown.Load()
 			if isShutdown {
 				break
@@ -50,32 +129,121 @@ func (s *Server) listen() {
 			log.Printf("Error during accepting connection: %v\n", err)
 			continue
 		}
-		go s.handle(conn)
+		if !s.limited() {
+			s.conns.Add(1)
+			go func() {
+				defer s.conns.Done()
+				s.handle(conn)
+			}()
+			continue
+		}
+		ip := clientIP(conn)
+		if reason := s.acquire(ip); reason != "" {
+			if s.metrics != nil {
+				s.metrics.rejectedConnections.Inc(reason)
+			}
+			go s.reject(conn)
+			continue
+		}
+		s.conns.Add(1)
+		go func() {
+			defer s.conns.Done()
+			defer s.release(ip)
+			s.handle(conn)
+		}()
 	}
 }
 
-func (s *Server) handle(conn net.Conn) {
-	defer conn.Close()
-	req, err := request.RequestFromReader(conn)
+func (s *Server) handle(netConn net.Conn) {
+	c := &conn{netConn: netConn, metrics: s.metrics}
+	defer c.close()
+	var reader io.Reader = netConn
+	if s.metrics != nil {
+		s.metrics.activeConnections.Inc()
+		defer s.metrics.activeConnections.Dec()
+		reader = countingReader{r: netConn, m: s.metrics}
+	}
+	addr := remoteAddr(netConn)
+	if s.sendsProxyHeader(netConn) {
+		client, rest, err := readProxyHeader(reader)
+		if err != nil {
+			log.Printf("Error reading PROXY header from %s: %v\n", netConn.RemoteAddr(), err)
+			return
+		}
+		if client != "" {
+			addr = client
+		}
+		reader = rest
+	}
+	req, err := request.RequestFromReader(reader)
 	if err != nil {
+		if s.metrics != nil {
+			s.metrics.observeParseError(err)
+		}
 		errH := HandlerError{Message: err.Error(), Code: response.StatusBadRequest}
-		errH.WriteError(conn)
+		errH.WriteError(c)
 		return
 	}
-	writer := response.Writer{Writer: conn}
-	s.handler(&writer, req)
+	c.buffered = req.Buffered()
+	req.RemoteAddr = addr
+
+	ctx, cancel := context.WithCancel(s.baseCtx)
+	defer cancel()
+	if s.writeTimeout > 0 {
+		netConn.SetWriteDeadline(time.Now().Add(s.writeTimeout))
+		ctx, cancel = context.WithTimeout(ctx, s.writeTimeout)
+		defer cancel()
+	}
+	c.watchDisconnect(cancel)
+	defer c.abortBackgroundRead()
+
+	writer := response.Writer{Writer: c}
+	if s.metrics == nil {
+		s.handler(&writer, req.WithContext(ctx))
+		return
+	}
+	start := time.Now()
+	stats := response.NewStatsWriter(&writer)
+	s.handler(&writer, req.WithContext(ctx))
+	s.metrics.observeRequest(req.RequestLine.Method, int(stats.Status()), start)
 }
 
-func Serve(port int, handler Handler) (*Server, error) {
-	address := ":" + strconv.Itoa(port)
-	listener, err := net.Listen("tcp", address)
+// Serve listens on TCP port on all interfaces and serves connections with
+// handler.
+func Serve(port int, handler Handler, opts ...Option) (*Server, error) {
+	return ServeAddr(":"+strconv.Itoa(port), handler, opts...)
+}
+
+// ServeAddr listens on the TCP address addr, e.g. "127.0.0.1:8080", and
+// serves connections with handler.
+func ServeAddr(addr string, handler Handler, opts ...Option) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
 	if err != nil {
 		return nil, err
 	}
+	return ServeListener(listener, handler, opts...), nil
+}
+
+// ServeListener serves connections accepted from an already opened
+// listener, such as one from ListenUnix or SystemdListeners. Close closes
+// the listener.
+func ServeListener(listener net.Listener, handler Handler, opts ...Option) *Server {
 	server := &Server{
-		listener: listener,
-		handler:  handler,
+		listener:   listener,
+		handler:    handler,
+		baseCtx:    context.Background(),
+		quit:       make(chan struct{}),
+		listenDone: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(server)
 	}
+	server.baseCtx, server.cancelBase = context.WithCancel(server.baseCtx)
 	go server.listen()
-	return server, nil
+	return server
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
 }
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/metrics"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitForCancel is a handler reporting why its request context ended.
+func waitForCancel(started chan<- struct{}, result chan<- error) Handler {
+	return func(w *response.Writer, req *request.Request) {
+		close(started)
+		select {
+		case <-req.Context().Done():
+			result <- req.Context().Err()
+		case <-time.After(5 * time.Second):
+			result <- errors.New("context not cancelled")
+		}
+	}
+}
+
+func dial(t *testing.T, s *Server) net.Conn {
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	return conn
+}
+
+func TestRequestContextCancelledOnDisconnect(t *testing.T) {
+	started, result := make(chan struct{}), make(chan error, 1)
+	s, err := Serve(0, waitForCancel(started, result))
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn := dial(t, s)
+	<-started
+	conn.Close()
+	assert.ErrorIs(t, <-result, context.Canceled)
+}
+
+func TestRequestContextCancelledOnClose(t *testing.T) {
+	started, result := make(chan struct{}), make(chan error, 1)
+	s, err := Serve(0, waitForCancel(started, result))
+	require.NoError(t, err)
+
+	conn := dial(t, s)
+	defer conn.Close()
+	<-started
+	s.Close()
+	assert.ErrorIs(t, <-result, context.Canceled)
+}
+
+func TestRequestContextWriteTimeout(t *testing.T) {
+	started, result := make(chan struct{}), make(chan error, 1)
+	s, err := Serve(0, waitForCancel(started, result), WithWriteTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn := dial(t, s)
+	defer conn.Close()
+	<-started
+	assert.ErrorIs(t, <-result, context.DeadlineExceeded)
+}
+
+type valueKey struct{}
+
+func TestRequestContextBaseValues(t *testing.T) {
+	base := context.WithValue(context.Background(), valueKey{}, "server")
+	s, err := Serve(0, func(w *response.Writer, req *request.Request) {
+		body := req.Context().Value(valueKey{}).(string)
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(response.GetDefaultHeaders(len(body)))
+		w.WriteBody([]byte(body))
+	}, WithBaseContext(base))
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn := dial(t, s)
+	defer conn.Close()
+	data, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "\r\n\r\nserver")
+}
+
+func TestHijackKeepsPipelinedBytes(t *testing.T) {
+	got := make(chan string, 1)
+	s, err := Serve(0, func(w *response.Writer, req *request.Request) {
+		// Give the disconnect watcher time to consume the next byte.
+		time.Sleep(50 * time.Millisecond)
+		conn, buffered, err := w.Hijack()
+		if err != nil {
+			got <- err.Error()
+			return
+		}
+		defer conn.Close()
+		rest := make([]byte, 5-len(buffered))
+		io.ReadFull(conn, rest)
+		got <- string(buffered) + string(rest)
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn := dial(t, s)
+	defer conn.Close()
+	time.Sleep(20 * time.Millisecond)
+	conn.Write([]byte("hello"))
+	assert.Equal(t, "hello", <-got)
+}
+
+func TestServerMetrics(t *testing.T) {
+	m := NewMetrics(metrics.NewRegistry())
+	s, err := Serve(0, func(w *response.Writer, req *request.Request) {
+		assert.Equal(t, float64(1), m.activeConnections.Value())
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(response.GetDefaultHeaders(2))
+		w.WriteBody([]byte("ok"))
+	}, WithMetrics(m))
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn := dial(t, s)
+	data, err := io.ReadAll(conn)
+	conn.Close()
+	require.NoError(t, err)
+
+	conn, err = net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	conn.Write([]byte("BREW / HTTP/1.1\r\n\r\n"))
+	io.ReadAll(conn)
+	conn.Close()
+
+	// The server records a request after the response is sent.
+	assert.Eventually(t, func() bool {
+		return m.requests.Value("GET", "200") == 1 && m.parseErrors.Value(request.ErrorKindRequestLine) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, uint64(1), m.duration.Count("GET"))
+	assert.GreaterOrEqual(t, m.bytesOut.Value(), float64(len(data)))
+	assert.GreaterOrEqual(t, m.bytesIn.Value(), float64(len("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")))
+	assert.Eventually(t, func() bool {
+		return m.activeConnections.Value() == 0
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,56 @@
+package sse
+
+import (
+	"strconv"
+	"sync"
+)
+
+// History keeps the latest events of a stream so that a reconnecting
+// client can be sent the ones it missed. It numbers the events it is
+// given, which makes their IDs ordered.
+type History struct {
+	mu     sync.Mutex
+	size   int
+	events []Event
+	next   uint64
+}
+
+// NewHistory returns a History keeping the last size events.
+func NewHistory(size int) *History {
+	return &History{size: size, next: 1}
+}
+
+// Add records e with the next ID and returns it as recorded.
+func (h *History) Add(e Event) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	e.ID = strconv.FormatUint(h.next, 10)
+	h.next++
+	h.events = append(h.events, e)
+	if len(h.events) > h.size {
+		h.events = h.events[len(h.events)-h.size:]
+	}
+	return e
+}
+
+// Since returns the recorded events after the one with ID lastEventID, as
+// reported by Stream.LastEventID. It returns none for "", a first
+// connection, and every recorded event if the ID is too old to be kept.
+func (h *History) Since(lastEventID string) []Event {
+	if lastEventID == "" {
+		return nil
+	}
+	last, err := strconv.ParseUint(lastEventID, 10, 64)
+	if err != nil {
+		last = 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var events []Event
+	for _, e := range h.events {
+		if id, _ := strconv.ParseUint(e.ID, 10, 64); id > last {
+			events = append(events, e)
+		}
+	}
+	return events
+}
@@ -0,0 +1,236 @@
+package sse
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+)
+
+// ContentType is the media type of an event stream.
+const ContentType = "text/event-stream"
+
+// DefaultHeartbeat is how often a Stream sends a comment while idle unless
+// the Streamer says otherwise.
+const DefaultHeartbeat = 15 * time.Second
+
+// Event is a message of an event stream, see the HTML Living Standard
+// section 9.2 "Server-sent events".
+type Event struct {
+	// ID becomes the client's last event ID, sent back as Last-Event-ID
+	// when it reconnects.
+	ID string
+	// Event is the event type; empty means "message".
+	Event string
+	// Data is the payload; it may span several lines.
+	Data string
+	// Retry sets the client's reconnection delay; 0 leaves it unchanged.
+	Retry time.Duration
+}
+
+// Streamer starts event streams.
+type Streamer struct {
+	// Heartbeat is how often a comment is sent while no event is, so that
+	// proxies keep the connection open and a gone client is noticed; 0
+	// means DefaultHeartbeat, a negative value disables heartbeats.
+	Heartbeat time.Duration
+	// Retry is sent as the reconnection delay when the stream starts; 0
+	// leaves the client's default.
+	Retry time.Duration
+}
+
+// Start starts an event stream with the default Streamer.
+func Start(w *response.Writer, req *request.Request) (*Stream, error) {
+	var s Streamer
+	return s.Start(w, req)
+}
+
+// Start answers req with the headers of an event stream sent chunked and
+// returns the Stream to send events on. The stream ends when the request
+// context does, e.g. once the client disconnects; the caller must Close
+// it.
+func (s *Streamer) Start(w *response.Writer, req *request.Request) (*Stream, error) {
+	if err := w.WriteStatusLine(response.StatusOK); err != nil {
+		return nil, err
+	}
+	h := response.ReplaceHeader(map[string]string{"Content-Type": ContentType}, response.GetChunkedHeaders())
+	h.Set("Cache-Control", "no-cache")
+	if err := w.WriteHeaders(h); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	stream := &Stream{
+		w:           w,
+		reqCtx:      req.Context(),
+		ctx:         ctx,
+		cancel:      cancel,
+		lastEventID: req.Headers.Get("last-event-id"),
+		done:        make(chan struct{}),
+	}
+	if s.Retry > 0 {
+		if err := stream.Send(Event{Retry: s.Retry}); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+	heartbeat := s.Heartbeat
+	if heartbeat == 0 {
+		heartbeat = DefaultHeartbeat
+	}
+	if heartbeat > 0 {
+		go stream.heartbeat(heartbeat)
+	} else {
+		close(stream.done)
+	}
+	return stream, nil
+}
+
+// Stream sends events to a client. Its methods may be called
+// concurrently.
+type Stream struct {
+	w           *response.Writer
+	reqCtx      context.Context
+	ctx         context.Context
+	cancel      context.CancelFunc
+	lastEventID string
+	// done is closed once the heartbeat goroutine has returned.
+	done      chan struct{}
+	mu        sync.Mutex
+	lastWrite time.Time
+	err       error
+}
+
+// LastEventID returns the ID of the last event the client received before
+// reconnecting, from the Last-Event-ID request header, or "" on a first
+// connection. Events after it should be sent again, see History.
+func (s *Stream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Context returns a context that ends with the stream, e.g. when the
+// client disconnects.
+func (s *Stream) Context() context.Context {
+	return s.ctx
+}
+
+// Send writes e to the client at once. It fails once the stream has ended.
+func (s *Stream) Send(e Event) error {
+	data, err := e.marshal()
+	if err != nil {
+		return err
+	}
+	return s.write(data)
+}
+
+// Comment writes a comment, which clients ignore.
+func (s *Stream) Comment(text string) error {
+	var b strings.Builder
+	for _, line := range splitLines(text) {
+		b.WriteString(": " + line + "\n")
+	}
+	b.WriteString("\n")
+	return s.write([]byte(b.String()))
+}
+
+// Close ends the stream: the heartbeats stop and, unless the client is
+// gone, the end of the body is written.
+func (s *Stream) Close() error {
+	s.cancel()
+	<-s.done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ended := s.err != nil || s.reqCtx.Err() != nil
+	s.err = context.Canceled
+	if ended {
+		return nil
+	}
+	if _, err := s.w.WriteChunkedBodyDone(); err != nil {
+		return err
+	}
+	return s.w.WriteTrailers(nil)
+}
+
+func (s *Stream) write(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.ctx.Err(); err != nil {
+		s.err = err
+		return err
+	}
+	// A chunk is written straight to the connection, so the event is not
+	// held back in a buffer.
+	if _, err := s.w.WriteChunkedBody(data); err != nil {
+		s.err = err
+		s.cancel()
+		return err
+	}
+	s.lastWrite = time.Now()
+	return nil
+}
+
+func (s *Stream) heartbeat(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			idle := time.Since(s.lastWrite) >= interval
+			s.mu.Unlock()
+			if !idle {
+				continue
+			}
+			if s.Comment("heartbeat") != nil {
+				return
+			}
+		}
+	}
+}
+
+// marshal encodes e in the event stream format. ID and Event must be a
+// single line, or the client would read the rest as other fields.
+func (e Event) marshal() ([]byte, error) {
+	if strings.ContainsAny(e.ID, "\r\n\x00") {
+		return nil, fmt.Errorf("Invalid event ID: %q", e.ID)
+	}
+	if strings.ContainsAny(e.Event, "\r\n") {
+		return nil, fmt.Errorf("Invalid event type: %q", e.Event)
+	}
+	var b strings.Builder
+	if e.ID != "" {
+		b.WriteString("id: " + e.ID + "\n")
+	}
+	if e.Event != "" {
+		b.WriteString("event: " + e.Event + "\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	// An event carrying only a retry is not dispatched to the page, one
+	// with data is, even if the data is empty.
+	if e.Data != "" || e.ID != "" || e.Event != "" || e.Retry == 0 {
+		for _, line := range splitLines(e.Data) {
+			b.WriteString("data: " + line + "\n")
+		}
+	}
+	b.WriteString("\n")
+	return []byte(b.String()), nil
+}
+
+// splitLines splits s at CRLF, LF or CR, the line endings of the format.
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	return strings.Split(s, "\n")
+}
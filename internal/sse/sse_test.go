@@ -0,0 +1,154 @@
+package sse
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/danielNemeth19/http-protocol/internal/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serve(t *testing.T, h server.Handler) string {
+	s, err := server.ServeAddr("127.0.0.1:0", h)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return "http://" + s.Addr().String() + "/events"
+}
+
+func TestEventMarshal(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		event Event
+		want  string
+	}{
+		{"data", Event{Data: "hello"}, "data: hello\n\n"},
+		{"empty data", Event{}, "data: \n\n"},
+		{"all fields", Event{ID: "7", Event: "update", Data: "x", Retry: 2500 * time.Millisecond}, "id: 7\nevent: update\nretry: 2500\ndata: x\n\n"},
+		{"multi-line data", Event{Data: "a\nb\r\nc\rd"}, "data: a\ndata: b\ndata: c\ndata: d\n\n"},
+		{"retry only", Event{Retry: time.Second}, "retry: 1000\n\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := tc.event.marshal()
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, string(data))
+		})
+	}
+
+	_, err := Event{ID: "1\ndata: forged"}.marshal()
+	assert.Error(t, err)
+	_, err = Event{Event: "a\rb"}.marshal()
+	assert.Error(t, err)
+}
+
+func TestStream(t *testing.T) {
+	history := NewHistory(10)
+	for i := range 3 {
+		history.Add(Event{Data: "event " + strconv.Itoa(i+1)})
+	}
+	url := serve(t, func(w *response.Writer, req *request.Request) {
+		streamer := Streamer{Heartbeat: -1, Retry: 3 * time.Second}
+		stream, err := streamer.Start(w, req)
+		if err != nil {
+			return
+		}
+		defer stream.Close()
+		for _, e := range history.Since(stream.LastEventID()) {
+			stream.Send(e)
+		}
+		stream.Send(Event{Event: "done", Data: "bye"})
+	})
+
+	req, err := http.NewRequest("GET", url, nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, ContentType, resp.Header.Get("Content-Type"))
+	assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+	assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "retry: 3000\n\n"+
+		"id: 2\ndata: event 2\n\n"+
+		"id: 3\ndata: event 3\n\n"+
+		"event: done\ndata: bye\n\n", string(body))
+}
+
+func TestStreamHeartbeat(t *testing.T) {
+	url := serve(t, func(w *response.Writer, req *request.Request) {
+		stream, err := (&Streamer{Heartbeat: 20 * time.Millisecond}).Start(w, req)
+		if err != nil {
+			return
+		}
+		defer stream.Close()
+		<-stream.Context().Done()
+	})
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, ": heartbeat\n", line)
+}
+
+func TestStreamClientDisconnect(t *testing.T) {
+	result := make(chan error, 1)
+	url := serve(t, func(w *response.Writer, req *request.Request) {
+		stream, err := Start(w, req)
+		if err != nil {
+			result <- err
+			return
+		}
+		defer stream.Close()
+		for {
+			if err := stream.Send(Event{Data: "tick"}); err != nil {
+				result <- err
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "data: tick\n", line)
+	resp.Body.Close()
+
+	select {
+	case err := <-result:
+		assert.Error(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stream kept sending after the client disconnected")
+	}
+}
+
+func TestHistory(t *testing.T) {
+	h := NewHistory(2)
+	for _, data := range []string{"a", "b", "c"} {
+		h.Add(Event{Data: data})
+	}
+	data := func(events []Event) string {
+		var s []string
+		for _, e := range events {
+			s = append(s, e.ID+"="+e.Data)
+		}
+		return strings.Join(s, ",")
+	}
+	assert.Empty(t, h.Since(""))
+	assert.Equal(t, "3=c", data(h.Since("2")))
+	assert.Empty(t, h.Since("3"))
+	// Test: Clients too far behind get every event kept
+	assert.Equal(t, "2=b,3=c", data(h.Since("0")))
+	assert.Equal(t, "2=b,3=c", data(h.Since("unknown")))
+}
@@ -0,0 +1,186 @@
+package vhost
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/danielNemeth19/http-protocol/internal/server"
+)
+
+// Router dispatches requests to a site's Handler by the host they are
+// addressed to.
+type Router struct {
+	// Default serves hosts no site matches; nil answers them with 421
+	// Misdirected Request.
+	Default server.Handler
+
+	exact     map[string]server.Handler
+	wildcards []wildcard
+}
+
+// wildcard is a "*.suffix" site; port is empty if any port matches.
+type wildcard struct {
+	suffix  string
+	port    string
+	handler server.Handler
+}
+
+func NewRouter() *Router {
+	return &Router{exact: make(map[string]server.Handler)}
+}
+
+// Add routes requests for pattern to h. A pattern is a host name or IP
+// address, optionally with a port: "example.com" matches any port,
+// "example.com:8080" only that one. "*.example.com" matches every
+// subdomain of example.com; the most specific match wins.
+func (r *Router) Add(pattern string, h server.Handler) error {
+	host, port, err := splitHost(pattern)
+	if err != nil {
+		return fmt.Errorf("Invalid host pattern %q: %v", pattern, err)
+	}
+	if suffix, found := strings.CutPrefix(host, "*."); found {
+		if suffix == "" || strings.Contains(suffix, "*") {
+			return fmt.Errorf("Invalid host pattern: %q", pattern)
+		}
+		r.wildcards = append(r.wildcards, wildcard{suffix: "." + suffix, port: port, handler: h})
+		// Longer suffixes are more specific; a port beats any port.
+		slices.SortStableFunc(r.wildcards, func(a, b wildcard) int {
+			if len(a.suffix) != len(b.suffix) {
+				return len(b.suffix) - len(a.suffix)
+			}
+			if (a.port == "") != (b.port == "") {
+				if a.port == "" {
+					return 1
+				}
+				return -1
+			}
+			return 0
+		})
+		return nil
+	}
+	if strings.Contains(host, "*") {
+		return fmt.Errorf("Invalid host pattern: %q", pattern)
+	}
+	r.exact[net.JoinHostPort(host, port)] = h
+	return nil
+}
+
+// Handle is a server.Handler dispatching req. A request without exactly
+// one valid Host gets 400 Bad Request, RFC 9112 section 3.2.
+func (r *Router) Handle(w *response.Writer, req *request.Request) {
+	host, port, err := requestHost(req)
+	if err != nil {
+		writeError(w, response.StatusBadRequest, err.Error()+"\n")
+		return
+	}
+	if h := r.match(host, port); h != nil {
+		h(w, req)
+		return
+	}
+	if r.Default != nil {
+		r.Default(w, req)
+		return
+	}
+	writeError(w, response.StatusMisdirectedRequest, "Unknown host: "+host+"\n")
+}
+
+func (r *Router) match(host, port string) server.Handler {
+	if h, ok := r.exact[net.JoinHostPort(host, port)]; ok && port != "" {
+		return h
+	}
+	if h, ok := r.exact[net.JoinHostPort(host, "")]; ok {
+		return h
+	}
+	for _, wc := range r.wildcards {
+		if strings.HasSuffix(host, wc.suffix) && (wc.port == "" || wc.port == port) {
+			return wc.handler
+		}
+	}
+	return nil
+}
+
+// requestHost returns the normalised host and port a request is for. For
+// absolute-form targets that is the target's authority, which overrides
+// Host (RFC 9112 section 3.2.2), but Host still has to be present.
+func requestHost(req *request.Request) (string, string, error) {
+	value, ok := req.Headers["host"]
+	if !ok {
+		return "", "", fmt.Errorf("Missing Host header")
+	}
+	// Repeated fields are joined with commas while parsing; a valid
+	// host never contains one.
+	if strings.Contains(value, ",") {
+		return "", "", fmt.Errorf("Multiple Host headers")
+	}
+	authority := strings.TrimSpace(value)
+	if target := req.RequestLine.RequestTarget; !strings.HasPrefix(target, "/") && strings.Contains(target, "://") {
+		u, err := url.Parse(target)
+		if err != nil {
+			return "", "", err
+		}
+		authority = u.Host
+	}
+	if authority == "" {
+		return "", "", fmt.Errorf("Empty Host header")
+	}
+	host, port, err := splitHost(authority)
+	if err == nil && strings.Contains(host, "*") {
+		err = fmt.Errorf("invalid character '*'")
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("Invalid Host header: %v", err)
+	}
+	return host, port, nil
+}
+
+// splitHost splits and validates host[:port], returning a lowercase host
+// without a trailing dot or IPv6 brackets.
+func splitHost(s string) (string, string, error) {
+	host, port := s, ""
+	if h, p, err := net.SplitHostPort(s); err == nil {
+		host, port = h, p
+		if port == "" {
+			return "", "", fmt.Errorf("empty port")
+		}
+		for _, c := range port {
+			if c < '0' || c > '9' {
+				return "", "", fmt.Errorf("invalid port %q", port)
+			}
+		}
+	} else if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		host = s[1 : len(s)-1]
+	}
+	if strings.Contains(host, ":") {
+		if net.ParseIP(host) == nil {
+			return "", "", fmt.Errorf("invalid address %q", host)
+		}
+		return strings.ToLower(host), port, nil
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" {
+		return "", "", fmt.Errorf("empty host")
+	}
+	for _, c := range host {
+		if !isHostChar(c) {
+			return "", "", fmt.Errorf("invalid character %q", c)
+		}
+	}
+	return host, port, nil
+}
+
+// isHostChar reports whether c may appear in a reg-name, RFC 3986 section
+// 3.2.2, leaving out percent-encoding and sub-delims other than the
+// wildcard of patterns.
+func isHostChar(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == '_' || c == '*'
+}
+
+func writeError(w *response.Writer, code response.StatusCode, msg string) {
+	errH := server.HandlerError{Code: code, Message: msg}
+	errH.WriteError(w.Writer)
+}
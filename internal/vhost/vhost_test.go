@@ -0,0 +1,102 @@
+package vhost
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/danielNemeth19/http-protocol/internal/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// site answers with its name.
+func site(name string) server.Handler {
+	return func(w *response.Writer, req *request.Request) {
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(response.GetDefaultHeaders(len(name)))
+		w.WriteBody([]byte(name))
+	}
+}
+
+func serve(t *testing.T, r *Router, target string, fields headers.Headers) (int, string) {
+	req := &request.Request{
+		RequestLine: request.RequestLine{Method: "GET", RequestTarget: target, HttpVersion: "1.1"},
+		Headers:     fields,
+	}
+	var buf bytes.Buffer
+	r.Handle(&response.Writer{Writer: &buf}, req)
+	resp, err := http.ReadResponse(bufio.NewReader(&buf), nil)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestRouter(t *testing.T) {
+	r := NewRouter()
+	require.NoError(t, r.Add("example.com", site("example")))
+	require.NoError(t, r.Add("example.com:8443", site("example-8443")))
+	require.NoError(t, r.Add("*.example.com", site("any-sub")))
+	require.NoError(t, r.Add("*.api.example.com", site("api-sub")))
+	require.NoError(t, r.Add("*.example.com:9000", site("sub-9000")))
+	require.NoError(t, r.Add("[::1]:8080", site("ipv6")))
+	require.NoError(t, r.Add("127.0.0.1", site("ipv4")))
+
+	for host, expected := range map[string]string{
+		"example.com":          "example",
+		"EXAMPLE.com.":         "example",
+		"example.com:80":       "example",
+		"example.com:8443":     "example-8443",
+		"www.example.com":      "any-sub",
+		"a.b.example.com:8443": "any-sub",
+		"v1.api.example.com":   "api-sub",
+		"www.example.com:9000": "sub-9000",
+		"[::1]:8080":           "ipv6",
+		"127.0.0.1:42069":      "ipv4",
+	} {
+		code, body := serve(t, r, "/", headers.Headers{"host": host})
+		assert.Equal(t, http.StatusOK, code, host)
+		assert.Equal(t, expected, body, host)
+	}
+
+	// Test: Unknown hosts
+	code, _ := serve(t, r, "/", headers.Headers{"host": "example.org"})
+	assert.Equal(t, http.StatusMisdirectedRequest, code)
+	r.Default = site("default")
+	_, body := serve(t, r, "/", headers.Headers{"host": "example.org"})
+	assert.Equal(t, "default", body)
+
+	// Test: Absolute-form target overrides Host
+	_, body = serve(t, r, "http://www.example.com/path", headers.Headers{"host": "example.org"})
+	assert.Equal(t, "any-sub", body)
+}
+
+func TestRouterRejectsInvalidHost(t *testing.T) {
+	r := NewRouter()
+	r.Default = site("default")
+	for _, fields := range []headers.Headers{
+		headers.NewHeaders(),
+		{"host": ""},
+		{"host": "example.com,example.org"},
+		{"host": "exa mple.com"},
+		{"host": "example.com:http"},
+		{"host": "*.example.com"},
+		{"host": "[::1"},
+	} {
+		code, _ := serve(t, r, "/", fields)
+		assert.Equal(t, http.StatusBadRequest, code, fields)
+	}
+}
+
+func TestRouterAddRejectsInvalidPattern(t *testing.T) {
+	r := NewRouter()
+	for _, pattern := range []string{"", "*.", "www.*.com", "*.*.com", "example.com:x"} {
+		assert.Error(t, r.Add(pattern, site("x")), pattern)
+	}
+}
@@ -0,0 +1,356 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+type MessageType int
+
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+const (
+	opContinuation byte = 0x0
+	opText         byte = 0x1
+	opBinary       byte = 0x2
+	opClose        byte = 0x8
+	opPing         byte = 0x9
+	opPong         byte = 0xA
+)
+
+// Close status codes, RFC 6455 section 7.4.1.
+const (
+	CloseNormalClosure    = 1000
+	CloseGoingAway        = 1001
+	CloseProtocolError    = 1002
+	CloseUnsupportedData  = 1003
+	CloseNoStatusReceived = 1005
+	CloseInvalidPayload   = 1007
+	ClosePolicyViolation  = 1008
+	CloseMessageTooBig    = 1009
+	CloseInternalError    = 1011
+)
+
+// DefaultReadLimit is the maximum message size accepted by a Conn unless
+// the Upgrader says otherwise.
+const DefaultReadLimit = 1 << 20
+
+const closeTimeout = 5 * time.Second
+
+var errCloseSent = errors.New("WebSocket close frame already sent")
+
+// CloseError is returned by ReadMessage once the connection is closing.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("WebSocket closed: %d %s", e.Code, e.Text)
+}
+
+type frame struct {
+	fin     bool
+	opcode  byte
+	payload []byte
+}
+
+// Conn is a WebSocket connection. Reads must come from a single goroutine;
+// writes may be issued concurrently.
+type Conn struct {
+	conn          net.Conn
+	br            *bufio.Reader
+	isServer      bool
+	subprotocol   string
+	readLimit     int64
+	pongHandler   func(data []byte)
+	writeMu       sync.Mutex
+	closeSent     bool
+	closeReceived bool
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, isServer bool) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	return &Conn{
+		conn:      conn,
+		br:        br,
+		isServer:  isServer,
+		readLimit: DefaultReadLimit,
+	}
+}
+
+// Subprotocol returns the subprotocol selected during the handshake.
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// SetPongHandler sets the function called with the payload of every pong.
+func (c *Conn) SetPongHandler(h func(data []byte)) {
+	c.pongHandler = h
+}
+
+// ReadMessage returns the next data message, reassembling fragments. Pings
+// are answered automatically. When the peer starts the closing handshake
+// the close frame is echoed and a *CloseError is returned.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	var msgType MessageType
+	var message []byte
+	fragmented := false
+	for {
+		f, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch f.opcode {
+		case opPing:
+			if err := c.writeFrame(true, opPong, f.payload); err != nil && err != errCloseSent {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			if c.pongHandler != nil {
+				c.pongHandler(f.payload)
+			}
+			continue
+		case opClose:
+			return 0, nil, c.handleClose(f.payload)
+		case opContinuation:
+			if !fragmented {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		case opText, opBinary:
+			if fragmented {
+				return 0, nil, c.fail(CloseProtocolError, "expected continuation frame")
+			}
+			msgType = MessageType(f.opcode)
+		default:
+			return 0, nil, c.fail(CloseProtocolError, "reserved opcode")
+		}
+		if int64(len(message)+len(f.payload)) > c.readLimit {
+			return 0, nil, c.fail(CloseMessageTooBig, "message too big")
+		}
+		message = append(message, f.payload...)
+		if f.fin {
+			if msgType == TextMessage && !utf8.Valid(message) {
+				return 0, nil, c.fail(CloseInvalidPayload, "invalid UTF-8 in text message")
+			}
+			return msgType, message, nil
+		}
+		fragmented = true
+	}
+}
+
+func (c *Conn) readFrame() (*frame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return nil, err
+	}
+	f := &frame{
+		fin:    head[0]&0x80 != 0,
+		opcode: head[0] & 0x0f,
+	}
+	if head[0]&0x70 != 0 {
+		return nil, c.fail(CloseProtocolError, "reserved bits set")
+	}
+	masked := head[1]&0x80 != 0
+	if masked != c.isServer {
+		return nil, c.fail(CloseProtocolError, "invalid frame masking")
+	}
+	length := uint64(head[1] & 0x7f)
+	isControl := f.opcode&0x8 != 0
+	if isControl && (!f.fin || length > 125) {
+		return nil, c.fail(CloseProtocolError, "invalid control frame")
+	}
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+		if length>>63 != 0 {
+			return nil, c.fail(CloseProtocolError, "invalid payload length")
+		}
+	}
+	if length > uint64(c.readLimit) {
+		return nil, c.fail(CloseMessageTooBig, "message too big")
+	}
+	var maskKey [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, maskKey[:]); err != nil {
+			return nil, err
+		}
+	}
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, f.payload); err != nil {
+		return nil, err
+	}
+	if masked {
+		maskBytes(maskKey, f.payload)
+	}
+	return f, nil
+}
+
+func (c *Conn) handleClose(payload []byte) error {
+	c.closeReceived = true
+	code, text := CloseNoStatusReceived, ""
+	switch {
+	case len(payload) == 1:
+		return c.fail(CloseProtocolError, "invalid close payload")
+	case len(payload) >= 2:
+		code = int(binary.BigEndian.Uint16(payload))
+		text = string(payload[2:])
+		if !validCloseCode(code) || !utf8.ValidString(text) {
+			return c.fail(CloseProtocolError, "invalid close payload")
+		}
+	}
+	echo := []byte{}
+	if code != CloseNoStatusReceived {
+		echo = closePayload(code, "")
+	}
+	if err := c.writeFrame(true, opClose, echo); err != nil && err != errCloseSent {
+		return err
+	}
+	return &CloseError{Code: code, Text: text}
+}
+
+// fail sends a close frame with the given code and closes the underlying
+// connection ("Fail the WebSocket Connection", RFC 6455 section 7.1.7).
+func (c *Conn) fail(code int, reason string) error {
+	c.writeFrame(true, opClose, closePayload(code, reason))
+	c.conn.Close()
+	return &CloseError{Code: code, Text: reason}
+}
+
+// WriteMessage sends data as a single, unfragmented message.
+func (c *Conn) WriteMessage(msgType MessageType, data []byte) error {
+	return c.writeFrame(true, byte(msgType), data)
+}
+
+// WriteFragments sends one message split into the given fragments.
+func (c *Conn) WriteFragments(msgType MessageType, fragments ...[]byte) error {
+	if len(fragments) == 0 {
+		return c.WriteMessage(msgType, nil)
+	}
+	opcode := byte(msgType)
+	for i, fragment := range fragments {
+		if err := c.writeFrame(i == len(fragments)-1, opcode, fragment); err != nil {
+			return err
+		}
+		opcode = opContinuation
+	}
+	return nil
+}
+
+// Ping sends a ping control frame; the payload must not exceed 125 bytes.
+func (c *Conn) Ping(data []byte) error {
+	if len(data) > 125 {
+		return fmt.Errorf("Ping payload too long: %d", len(data))
+	}
+	return c.writeFrame(true, opPing, data)
+}
+
+// Close starts the closing handshake, waits briefly for the peer's close
+// frame and closes the underlying connection.
+func (c *Conn) Close(code int, reason string) error {
+	err := c.writeFrame(true, opClose, closePayload(code, reason))
+	if err == nil && !c.closeReceived {
+		c.conn.SetReadDeadline(time.Now().Add(closeTimeout))
+		for {
+			f, err := c.readFrame()
+			if err != nil || f.opcode == opClose {
+				break
+			}
+		}
+	}
+	return c.conn.Close()
+}
+
+func (c *Conn) writeFrame(fin bool, opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return errCloseSent
+	}
+	if opcode == opClose {
+		c.closeSent = true
+	}
+
+	buf := make([]byte, 0, 14+len(payload))
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	buf = append(buf, b0)
+	var maskBit byte
+	if !c.isServer {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, maskBit|byte(n))
+	case n <= 0xffff:
+		buf = append(buf, maskBit|126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, maskBit|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+	if c.isServer {
+		buf = append(buf, payload...)
+	} else {
+		// Clients mask every frame with a fresh key, RFC 6455 section 5.3.
+		var maskKey [4]byte
+		if _, err := rand.Read(maskKey[:]); err != nil {
+			return err
+		}
+		buf = append(buf, maskKey[:]...)
+		start := len(buf)
+		buf = append(buf, payload...)
+		maskBytes(maskKey, buf[start:])
+	}
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i%4]
+	}
+}
+
+func closePayload(code int, reason string) []byte {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	return append(payload, reason...)
+}
+
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003:
+		return true
+	case code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+)
+
+// keyGUID is appended to Sec-WebSocket-Key to compute Sec-WebSocket-Accept,
+// see RFC 6455 section 1.3.
+const keyGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Upgrader validates opening handshakes and switches connections to the
+// WebSocket protocol.
+type Upgrader struct {
+	// Subprotocols lists the supported subprotocols in order of preference.
+	Subprotocols []string
+	// CheckOrigin rejects the handshake when it returns false. A nil
+	// CheckOrigin accepts every origin.
+	CheckOrigin func(req *request.Request) bool
+	// ReadLimit is the maximum size of a message in bytes; 0 means
+	// DefaultReadLimit.
+	ReadLimit int64
+}
+
+// Upgrade performs the handshake with the default Upgrader.
+func Upgrade(w *response.Writer, req *request.Request) (*Conn, error) {
+	var u Upgrader
+	return u.Upgrade(w, req)
+}
+
+// Upgrade validates the client handshake of req, answers it with
+// 101 Switching Protocols and hijacks the connection: the caller is
+// responsible for closing the returned Conn. On a failed handshake an
+// error response has already been written and the error is returned.
+func (u *Upgrader) Upgrade(w *response.Writer, req *request.Request) (*Conn, error) {
+	switch w.Writer.(type) {
+	case response.Hijacker, net.Conn:
+	default:
+		return nil, fmt.Errorf("Connection does not support upgrades")
+	}
+	if req.RequestLine.Method != "GET" {
+		return nil, u.fail(w, response.StatusBadRequest, "WebSocket handshake requires GET")
+	}
+	if !headerContainsToken(req.Headers, "connection", "upgrade") {
+		return nil, u.fail(w, response.StatusBadRequest, "Connection header must contain 'upgrade'")
+	}
+	if !headerContainsToken(req.Headers, "upgrade", "websocket") {
+		return nil, u.fail(w, response.StatusBadRequest, "Upgrade header must contain 'websocket'")
+	}
+	if req.Headers.Get("sec-websocket-version") != "13" {
+		return nil, u.fail(w, response.StatusUpgradeRequired, "Unsupported WebSocket version")
+	}
+	key := req.Headers.Get("sec-websocket-key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, u.fail(w, response.StatusBadRequest, "Invalid Sec-WebSocket-Key")
+	}
+	if u.CheckOrigin != nil && !u.CheckOrigin(req) {
+		return nil, u.fail(w, response.StatusForbidden, "Origin not allowed")
+	}
+
+	h := headers.NewHeaders()
+	h.Set("Upgrade", "websocket")
+	h.Set("Connection", "Upgrade")
+	h.Set("Sec-WebSocket-Accept", acceptKey(key))
+	subprotocol := u.selectSubprotocol(req)
+	if subprotocol != "" {
+		h.Set("Sec-WebSocket-Protocol", subprotocol)
+	}
+	if err := w.WriteStatusLine(response.StatusSwitchingProtocols); err != nil {
+		return nil, err
+	}
+	if err := w.WriteHeaders(h); err != nil {
+		return nil, err
+	}
+	conn, buffered, err := w.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	var br *bufio.Reader
+	if len(buffered) > 0 {
+		br = bufio.NewReader(io.MultiReader(bytes.NewReader(buffered), conn))
+	}
+	c := newConn(conn, br, true)
+	c.subprotocol = subprotocol
+	if u.ReadLimit > 0 {
+		c.readLimit = u.ReadLimit
+	}
+	return c, nil
+}
+
+func (u *Upgrader) fail(w *response.Writer, code response.StatusCode, message string) error {
+	h := response.GetDefaultHeaders(len(message))
+	if code == response.StatusUpgradeRequired {
+		h.Set("Sec-WebSocket-Version", "13")
+	}
+	w.WriteStatusLine(code)
+	w.WriteHeaders(h)
+	w.WriteBody([]byte(message))
+	return fmt.Errorf("WebSocket handshake failed: %s", message)
+}
+
+func (u *Upgrader) selectSubprotocol(req *request.Request) string {
+	for _, offered := range strings.Split(req.Headers.Get("sec-websocket-protocol"), ",") {
+		offered = strings.TrimSpace(offered)
+		if offered != "" && slices.Contains(u.Subprotocols, offered) {
+			return offered
+		}
+	}
+	return ""
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + keyGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func headerContainsToken(h headers.Headers, name, token string) bool {
+	for _, v := range strings.Split(h.Get(name), ",") {
+		if strings.EqualFold(strings.TrimSpace(v), token) {
+			return true
+		}
+	}
+	return false
+}
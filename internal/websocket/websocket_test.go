@@ -0,0 +1,166 @@
+package websocket
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/danielNemeth19/http-protocol/internal/headers"
+	"github.com/danielNemeth19/http-protocol/internal/request"
+	"github.com/danielNemeth19/http-protocol/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "dGhlIHNhbXBsZSBub25jZQ=="
+
+// startEchoServer accepts a single connection, upgrades it and echoes
+// every message back until the connection closes.
+func startEchoServer(t *testing.T, upgrader *Upgrader) (string, chan error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	result := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			result <- err
+			return
+		}
+		defer conn.Close()
+		req, err := request.RequestFromReader(conn)
+		if err != nil {
+			result <- err
+			return
+		}
+		ws, err := upgrader.Upgrade(&response.Writer{Writer: conn}, req)
+		if err != nil {
+			result <- err
+			return
+		}
+		for {
+			msgType, data, err := ws.ReadMessage()
+			if err != nil {
+				result <- err
+				return
+			}
+			ws.WriteMessage(msgType, data)
+		}
+	}()
+	return listener.Addr().String(), result
+}
+
+func dial(t *testing.T, addr string, extra ...string) (*Conn, string, headers.Headers) {
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	handshake := "GET /chat HTTP/1.1\r\n" +
+		"Host: " + addr + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + testKey + "\r\n" +
+		"Sec-WebSocket-Version: 13\r\n" +
+		strings.Join(extra, "") +
+		"\r\n"
+	_, err = conn.Write([]byte(handshake))
+	require.NoError(t, err)
+
+	br := bufio.NewReader(conn)
+	statusLine, err := br.ReadString('\n')
+	require.NoError(t, err)
+	h := headers.NewHeaders()
+	for {
+		line, err := br.ReadString('\n')
+		require.NoError(t, err)
+		_, done, err := h.Parse([]byte(line))
+		require.NoError(t, err)
+		if done {
+			break
+		}
+	}
+	return newConn(conn, br, false), strings.TrimSpace(statusLine), h
+}
+
+func TestAcceptKey(t *testing.T) {
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", acceptKey(testKey))
+}
+
+func TestUpgradeAndEcho(t *testing.T) {
+	addr, _ := startEchoServer(t, &Upgrader{Subprotocols: []string{"chat"}})
+	c, status, h := dial(t, addr, "Sec-WebSocket-Protocol: superchat, chat\r\n")
+	assert.Equal(t, "HTTP/1.1 101 Switching Protocols", status)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", h.Get("sec-websocket-accept"))
+	assert.Equal(t, "chat", h.Get("sec-websocket-protocol"))
+
+	require.NoError(t, c.WriteMessage(TextMessage, []byte("hello")))
+	msgType, data, err := c.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, TextMessage, msgType)
+	assert.Equal(t, "hello", string(data))
+
+	large := []byte(strings.Repeat("x", 70000))
+	require.NoError(t, c.WriteMessage(BinaryMessage, large))
+	msgType, data, err = c.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, BinaryMessage, msgType)
+	assert.Equal(t, large, data)
+}
+
+func TestFragmentedMessageWithInterleavedPing(t *testing.T) {
+	addr, _ := startEchoServer(t, &Upgrader{})
+	c, _, _ := dial(t, addr)
+
+	pongs := make(chan string, 1)
+	c.SetPongHandler(func(data []byte) { pongs <- string(data) })
+	require.NoError(t, c.writeFrame(false, opText, []byte("hel")))
+	require.NoError(t, c.Ping([]byte("are you there")))
+	require.NoError(t, c.writeFrame(true, opContinuation, []byte("lo")))
+
+	msgType, data, err := c.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, TextMessage, msgType)
+	assert.Equal(t, "hello", string(data))
+	assert.Equal(t, "are you there", <-pongs)
+}
+
+func TestCloseHandshake(t *testing.T) {
+	addr, result := startEchoServer(t, &Upgrader{})
+	c, _, _ := dial(t, addr)
+
+	require.NoError(t, c.writeFrame(true, opClose, closePayload(CloseGoingAway, "bye")))
+	_, _, err := c.ReadMessage()
+	var closeErr *CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, CloseGoingAway, closeErr.Code)
+
+	err = <-result
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, CloseGoingAway, closeErr.Code)
+	assert.Equal(t, "bye", closeErr.Text)
+}
+
+func TestUnmaskedClientFrameFailsConnection(t *testing.T) {
+	addr, result := startEchoServer(t, &Upgrader{})
+	c, _, _ := dial(t, addr)
+	c.isServer = true // write without masking
+	require.NoError(t, c.WriteMessage(TextMessage, []byte("hi")))
+
+	err := <-result
+	var closeErr *CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, CloseProtocolError, closeErr.Code)
+}
+
+func TestUpgradeRejectsBadHandshake(t *testing.T) {
+	addr, result := startEchoServer(t, &Upgrader{})
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: x\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Version: 8\r\nSec-WebSocket-Key: " + testKey + "\r\n\r\n"))
+	require.NoError(t, err)
+	statusLine, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 426 Upgrade Required\r\n", statusLine)
+	require.Error(t, <-result)
+}